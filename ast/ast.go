@@ -2,6 +2,8 @@ package ast
 
 import (
 	"bytes"
+	"math/big"
+	"strings"
 
 	"github.com/juanfgarcia/gorilla/token"
 )
@@ -21,6 +23,13 @@ type Expression interface {
 	expressionNode()
 }
 
+// Pattern is the left-hand side of a match arm. Identifiers bind the
+// matched value and literals compare equal to it.
+type Pattern interface {
+	Node
+	patternNode()
+}
+
 type Program struct {
 	Statements []Statement
 }
@@ -56,7 +65,9 @@ func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Name.String())
+	if ls.Name != nil {
+		out.WriteString(ls.Name.String())
+	}
 	out.WriteString(" = ")
 
 	if ls.Value != nil {
@@ -67,6 +78,60 @@ func (ls *LetStatement) String() string {
 	return out.String()
 }
 
+// DeclareStatement is a short declaration, x := value, which binds a
+// name that is new in its scope.
+type DeclareStatement struct {
+	Token token.Token
+	Name  *Identifier
+	Value Expression
+}
+
+func (ds *DeclareStatement) statementNode()       {}
+func (ds *DeclareStatement) TokenLiteral() string { return ds.Token.Literal }
+
+func (ds *DeclareStatement) String() string {
+	var out bytes.Buffer
+
+	if ds.Name != nil {
+		out.WriteString(ds.Name.String())
+	}
+	out.WriteString(" := ")
+	if ds.Value != nil {
+		out.WriteString(ds.Value.String())
+	}
+	out.WriteString(";")
+
+	return out.String()
+}
+
+// AssignStatement gives a new value to an existing binding. Operator is
+// "=" or a compound form such as "+=", which combines the old value with
+// Value using the operator before the "=".
+type AssignStatement struct {
+	Token    token.Token
+	Name     *Identifier
+	Operator string
+	Value    Expression
+}
+
+func (as *AssignStatement) statementNode()       {}
+func (as *AssignStatement) TokenLiteral() string { return as.Token.Literal }
+
+func (as *AssignStatement) String() string {
+	var out bytes.Buffer
+
+	if as.Name != nil {
+		out.WriteString(as.Name.String())
+	}
+	out.WriteString(" " + as.Operator + " ")
+	if as.Value != nil {
+		out.WriteString(as.Value.String())
+	}
+	out.WriteString(";")
+
+	return out.String()
+}
+
 type ReturnStatement struct {
 	Token       token.Token
 	ReturnValue Expression
@@ -118,7 +183,9 @@ func (pe *PrefixExpression) String() string {
 
 	out.WriteString("(")
 	out.WriteString(pe.Operator)
-	out.WriteString(pe.Right.String())
+	if pe.Right != nil {
+		out.WriteString(pe.Right.String())
+	}
 	out.WriteString(")")
 
 	return out.String()
@@ -138,9 +205,13 @@ func (ie *InfixExpression) String() string {
 	var out bytes.Buffer
 
 	out.WriteString("(")
-	out.WriteString(ie.Left.String())
+	if ie.Left != nil {
+		out.WriteString(ie.Left.String())
+	}
 	out.WriteString(" " + ie.Operator + " ")
-	out.WriteString(ie.Right.String())
+	if ie.Right != nil {
+		out.WriteString(ie.Right.String())
+	}
 	out.WriteString(")")
 
 	return out.String()
@@ -152,6 +223,7 @@ type Identifier struct {
 }
 
 func (i *Identifier) expressionNode()      {}
+func (i *Identifier) patternNode()         {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
 
 func (i *Identifier) String() string {
@@ -164,18 +236,47 @@ type IntegerLiteral struct {
 }
 
 func (il *IntegerLiteral) expressionNode()      {}
+func (il *IntegerLiteral) patternNode()         {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
 
 func (il *IntegerLiteral) String() string {
 	return il.TokenLiteral()
 }
 
+// BigIntegerLiteral is an integer literal too large for an int64.
+type BigIntegerLiteral struct {
+	Token token.Token
+	Value *big.Int
+}
+
+func (bl *BigIntegerLiteral) expressionNode()      {}
+func (bl *BigIntegerLiteral) patternNode()         {}
+func (bl *BigIntegerLiteral) TokenLiteral() string { return bl.Token.Literal }
+
+func (bl *BigIntegerLiteral) String() string {
+	return bl.TokenLiteral()
+}
+
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) patternNode()         {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+
+func (fl *FloatLiteral) String() string {
+	return fl.TokenLiteral()
+}
+
 type Boolean struct {
 	Token token.Token
 	Value bool
 }
 
 func (b *Boolean) expressionNode()      {}
+func (b *Boolean) patternNode()         {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
 
 func (b *Boolean) String() string {
@@ -201,7 +302,9 @@ func (fl *FunctionLiteral) String() string {
 	}
 
 	out.WriteString(" )")
-	out.WriteString(fl.Body.String())
+	if fl.Body != nil {
+		out.WriteString(fl.Body.String())
+	}
 
 	return out.String()
 }
@@ -220,9 +323,13 @@ func (ie *IfExpression) String() string {
 	var out bytes.Buffer
 
 	out.WriteString("if ")
-	out.WriteString(ie.Condition.String())
+	if ie.Condition != nil {
+		out.WriteString(ie.Condition.String())
+	}
 	out.WriteString(" ")
-	out.WriteString(ie.Consequence.String())
+	if ie.Consequence != nil {
+		out.WriteString(ie.Consequence.String())
+	}
 
 	if ie.Alternative != nil {
 		out.WriteString("else ")
@@ -237,6 +344,7 @@ type BlockStatement struct {
 	Statements []Statement
 }
 
+func (bs *BlockStatement) statementNode() {}
 func (bs *BlockStatement) TokenLiteral() string {
 	return bs.Token.Literal
 }
@@ -250,3 +358,279 @@ func (bs *BlockStatement) String() string {
 
 	return out.String()
 }
+
+type MacroLiteral struct {
+	Token      token.Token
+	Parameters []*Identifier
+	Body       *BlockStatement
+}
+
+func (ml *MacroLiteral) expressionNode()      {}
+func (ml *MacroLiteral) TokenLiteral() string { return ml.Token.Literal }
+func (ml *MacroLiteral) String() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range ml.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString(ml.TokenLiteral())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") ")
+	if ml.Body != nil {
+		out.WriteString(ml.Body.String())
+	}
+
+	return out.String()
+}
+
+type StringLiteral struct {
+	Token token.Token
+	Value string
+}
+
+func (sl *StringLiteral) expressionNode()      {}
+func (sl *StringLiteral) patternNode()         {}
+func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+
+func (sl *StringLiteral) String() string {
+	return sl.Token.Literal
+}
+
+// ImportStatement makes the names exported by the module at Path
+// visible in the importing module.
+type ImportStatement struct {
+	Token token.Token
+	Path  *StringLiteral
+}
+
+func (is *ImportStatement) statementNode()       {}
+func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
+
+func (is *ImportStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(is.TokenLiteral() + " ")
+	if is.Path != nil {
+		out.WriteString(is.Path.String())
+	}
+	out.WriteString(";")
+
+	return out.String()
+}
+
+// ExportStatement is a let statement whose binding can be imported by
+// other modules.
+type ExportStatement struct {
+	Token     token.Token
+	Statement *LetStatement
+}
+
+func (es *ExportStatement) statementNode()       {}
+func (es *ExportStatement) TokenLiteral() string { return es.Token.Literal }
+
+func (es *ExportStatement) String() string {
+	if es.Statement == nil {
+		return es.TokenLiteral() + " "
+	}
+	return es.TokenLiteral() + " " + es.Statement.String()
+}
+
+// WhileStatement runs Body for as long as Condition holds.
+type WhileStatement struct {
+	Token     token.Token
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode()       {}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+
+func (ws *WhileStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("while ")
+	if ws.Condition != nil {
+		out.WriteString(ws.Condition.String())
+	}
+	out.WriteString(" ")
+	if ws.Body != nil {
+		out.WriteString(ws.Body.String())
+	}
+
+	return out.String()
+}
+
+// ForStatement runs Body once for every element of Iterable, with
+// Variable bound to the element.
+type ForStatement struct {
+	Token    token.Token
+	Variable *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs *ForStatement) statementNode()       {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for ")
+	if fs.Variable != nil {
+		out.WriteString(fs.Variable.String())
+	}
+	out.WriteString(" in ")
+	if fs.Iterable != nil {
+		out.WriteString(fs.Iterable.String())
+	}
+	out.WriteString(" ")
+	if fs.Body != nil {
+		out.WriteString(fs.Body.String())
+	}
+
+	return out.String()
+}
+
+// BreakStatement leaves the innermost enclosing loop.
+type BreakStatement struct {
+	Token token.Token
+}
+
+func (bs *BreakStatement) statementNode()       {}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+
+func (bs *BreakStatement) String() string {
+	return bs.TokenLiteral() + ";"
+}
+
+// ContinueStatement skips to the next iteration of the innermost
+// enclosing loop.
+type ContinueStatement struct {
+	Token token.Token
+}
+
+func (cs *ContinueStatement) statementNode()       {}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+
+func (cs *ContinueStatement) String() string {
+	return cs.TokenLiteral() + ";"
+}
+
+// MatchExpression evaluates to the body of the first arm whose pattern
+// matches Subject and whose guard, if any, holds.
+type MatchExpression struct {
+	Token   token.Token
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+
+	arms := []string{}
+	for _, a := range me.Arms {
+		arms = append(arms, a.String())
+	}
+
+	out.WriteString("match ")
+	if me.Subject != nil {
+		out.WriteString(me.Subject.String())
+	}
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// MatchArm is one pattern => body arm of a match expression. Token is
+// the first token of the pattern.
+type MatchArm struct {
+	Token   token.Token
+	Pattern Pattern
+	Guard   Expression
+	Body    Expression
+}
+
+func (ma *MatchArm) TokenLiteral() string { return ma.Token.Literal }
+
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+
+	if ma.Pattern != nil {
+		out.WriteString(ma.Pattern.String())
+	}
+	if ma.Guard != nil {
+		out.WriteString(" if " + ma.Guard.String())
+	}
+	out.WriteString(" => ")
+	if ma.Body != nil {
+		out.WriteString(ma.Body.String())
+	}
+
+	return out.String()
+}
+
+// WildcardPattern, written _, matches any value without binding it.
+type WildcardPattern struct {
+	Token token.Token
+}
+
+func (wp *WildcardPattern) patternNode()         {}
+func (wp *WildcardPattern) TokenLiteral() string { return wp.Token.Literal }
+
+func (wp *WildcardPattern) String() string {
+	return wp.Token.Literal
+}
+
+// ArrayPattern matches an array element by element. Without Rest the
+// array must have exactly len(Elements) elements; with it, at least that
+// many, and Rest matches the array of the remaining ones.
+type ArrayPattern struct {
+	Token    token.Token
+	Elements []Pattern
+	Rest     Pattern
+}
+
+func (ap *ArrayPattern) patternNode()         {}
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+
+func (ap *ArrayPattern) String() string {
+	elements := []string{}
+	for _, e := range ap.Elements {
+		elements = append(elements, e.String())
+	}
+	if ap.Rest != nil {
+		elements = append(elements, "..."+ap.Rest.String())
+	}
+
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// HashPattern matches a hash that has every key in Keys, with the value
+// under Keys[i] matching Values[i]. Other keys are ignored.
+type HashPattern struct {
+	Token  token.Token
+	Keys   []Expression
+	Values []Pattern
+}
+
+func (hp *HashPattern) patternNode()         {}
+func (hp *HashPattern) TokenLiteral() string { return hp.Token.Literal }
+
+func (hp *HashPattern) String() string {
+	pairs := []string{}
+	for i, k := range hp.Keys {
+		if i < len(hp.Values) {
+			pairs = append(pairs, k.String()+": "+hp.Values[i].String())
+		}
+	}
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
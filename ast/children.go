@@ -0,0 +1,225 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/juanfgarcia/gorilla/token"
+)
+
+// Child is a node together with the role, named after the field,
+// that it plays in its parent.
+type Child struct {
+	Role string
+	Node Node
+}
+
+// Children returns the non-nil children of node in source order.
+func Children(node Node) []Child {
+	children := []Child{}
+
+	add := func(role string, child Node) {
+		children = append(children, Child{Role: role, Node: child})
+	}
+
+	switch node := node.(type) {
+	case *Program:
+		for _, s := range node.Statements {
+			add("statements", s)
+		}
+	case *LetStatement:
+		if node.Name != nil {
+			add("name", node.Name)
+		}
+		if node.Value != nil {
+			add("value", node.Value)
+		}
+	case *DeclareStatement:
+		if node.Name != nil {
+			add("name", node.Name)
+		}
+		if node.Value != nil {
+			add("value", node.Value)
+		}
+	case *AssignStatement:
+		if node.Name != nil {
+			add("name", node.Name)
+		}
+		if node.Value != nil {
+			add("value", node.Value)
+		}
+	case *ReturnStatement:
+		if node.ReturnValue != nil {
+			add("returnValue", node.ReturnValue)
+		}
+	case *ExpressionStatement:
+		if node.Expression != nil {
+			add("expression", node.Expression)
+		}
+	case *BlockStatement:
+		for _, s := range node.Statements {
+			add("statements", s)
+		}
+	case *PrefixExpression:
+		if node.Right != nil {
+			add("right", node.Right)
+		}
+	case *InfixExpression:
+		if node.Left != nil {
+			add("left", node.Left)
+		}
+		if node.Right != nil {
+			add("right", node.Right)
+		}
+	case *IfExpression:
+		if node.Condition != nil {
+			add("condition", node.Condition)
+		}
+		if node.Consequence != nil {
+			add("consequence", node.Consequence)
+		}
+		if node.Alternative != nil {
+			add("alternative", node.Alternative)
+		}
+	case *FunctionLiteral:
+		for _, p := range node.Parameters {
+			add("parameters", p)
+		}
+		if node.Body != nil {
+			add("body", node.Body)
+		}
+	case *MacroLiteral:
+		for _, p := range node.Parameters {
+			add("parameters", p)
+		}
+		if node.Body != nil {
+			add("body", node.Body)
+		}
+	case *ImportStatement:
+		if node.Path != nil {
+			add("path", node.Path)
+		}
+	case *ExportStatement:
+		if node.Statement != nil {
+			add("statement", node.Statement)
+		}
+	case *WhileStatement:
+		if node.Condition != nil {
+			add("condition", node.Condition)
+		}
+		if node.Body != nil {
+			add("body", node.Body)
+		}
+	case *MatchExpression:
+		if node.Subject != nil {
+			add("subject", node.Subject)
+		}
+		for _, a := range node.Arms {
+			add("arms", a)
+		}
+	case *MatchArm:
+		if node.Pattern != nil {
+			add("pattern", node.Pattern)
+		}
+		if node.Guard != nil {
+			add("guard", node.Guard)
+		}
+		if node.Body != nil {
+			add("body", node.Body)
+		}
+	case *ArrayPattern:
+		for _, e := range node.Elements {
+			add("elements", e)
+		}
+		if node.Rest != nil {
+			add("rest", node.Rest)
+		}
+	case *HashPattern:
+		for i, k := range node.Keys {
+			add("keys", k)
+			if i < len(node.Values) {
+				add("values", node.Values[i])
+			}
+		}
+	case *ForStatement:
+		if node.Variable != nil {
+			add("variable", node.Variable)
+		}
+		if node.Iterable != nil {
+			add("iterable", node.Iterable)
+		}
+		if node.Body != nil {
+			add("body", node.Body)
+		}
+	}
+
+	return children
+}
+
+// StartToken returns the token node begins with. A program has none.
+func StartToken(node Node) (token.Token, bool) {
+	switch node := node.(type) {
+	case *LetStatement:
+		return node.Token, true
+	case *ReturnStatement:
+		return node.Token, true
+	case *ExpressionStatement:
+		return node.Token, true
+	case *BlockStatement:
+		return node.Token, true
+	case *Identifier:
+		return node.Token, true
+	case *IntegerLiteral:
+		return node.Token, true
+	case *Boolean:
+		return node.Token, true
+	case *PrefixExpression:
+		return node.Token, true
+	case *InfixExpression:
+		return node.Token, true
+	case *IfExpression:
+		return node.Token, true
+	case *FunctionLiteral:
+		return node.Token, true
+	case *MacroLiteral:
+		return node.Token, true
+	case *StringLiteral:
+		return node.Token, true
+	case *ImportStatement:
+		return node.Token, true
+	case *ExportStatement:
+		return node.Token, true
+	case *FloatLiteral:
+		return node.Token, true
+	case *BigIntegerLiteral:
+		return node.Token, true
+	case *WhileStatement:
+		return node.Token, true
+	case *ForStatement:
+		return node.Token, true
+	case *BreakStatement:
+		return node.Token, true
+	case *ContinueStatement:
+		return node.Token, true
+	case *AssignStatement:
+		return node.Token, true
+	case *DeclareStatement:
+		return node.Token, true
+	case *MatchExpression:
+		return node.Token, true
+	case *MatchArm:
+		return node.Token, true
+	case *WildcardPattern:
+		return node.Token, true
+	case *ArrayPattern:
+		return node.Token, true
+	case *HashPattern:
+		return node.Token, true
+	}
+	return token.Token{}, false
+}
+
+// Kind returns the name of the node's type, such as "LetStatement".
+func Kind(node Node) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+}
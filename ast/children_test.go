@@ -0,0 +1,40 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/juanfgarcia/gorilla/ast"
+	"github.com/juanfgarcia/gorilla/parser"
+)
+
+func TestStartToken(t *testing.T) {
+	input := `import "lib";
+export let f = fn(x) { if (!x) { 1 + x } else { "s" } };
+macro(a) { a; };
+match f { [_, ...t] => t, {"k": v} if v => v };
+return 5;`
+
+	p := parser.New(input)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	if _, ok := ast.StartToken(program); ok {
+		t.Errorf("a program should have no start token")
+	}
+
+	var visit func(node ast.Node)
+	visit = func(node ast.Node) {
+		for _, child := range ast.Children(node) {
+			tok, ok := ast.StartToken(child.Node)
+			if !ok {
+				t.Errorf("%s has no start token", ast.Kind(child.Node))
+			} else if tok.Literal != child.Node.TokenLiteral() {
+				t.Errorf("%s starts with %q, want %q", ast.Kind(child.Node), tok.Literal, child.Node.TokenLiteral())
+			}
+			visit(child.Node)
+		}
+	}
+	visit(program)
+}
@@ -0,0 +1,106 @@
+// Package dump prints syntax trees in forms meant for humans debugging
+// the parser: an indented S-expression and a Graphviz DOT graph.
+package dump
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/juanfgarcia/gorilla/ast"
+)
+
+// SExpr returns the tree rooted at node as an indented S-expression,
+// one node per line, each child nested two spaces below its parent.
+func SExpr(node ast.Node) string {
+	var out bytes.Buffer
+	writeSExpr(&out, node, 0)
+	return out.String()
+}
+
+func writeSExpr(out *bytes.Buffer, node ast.Node, depth int) {
+	if depth > 0 {
+		out.WriteString("\n")
+		out.WriteString(strings.Repeat("  ", depth))
+	}
+
+	out.WriteString("(")
+	out.WriteString(ast.Kind(node))
+
+	if atom := atom(node); atom != "" {
+		out.WriteString(" ")
+		out.WriteString(atom)
+	}
+
+	for _, child := range ast.Children(node) {
+		writeSExpr(out, child.Node, depth+1)
+	}
+
+	out.WriteString(")")
+}
+
+// DOT returns the tree rooted at node as a Graphviz digraph with one
+// vertex per node, labeled with its type and token literal, and edges
+// labeled with the role each child plays in its parent.
+func DOT(node ast.Node) string {
+	var out bytes.Buffer
+
+	out.WriteString("digraph ast {\n")
+	out.WriteString("\tnode [shape=box];\n")
+
+	next := 0
+	var visit func(node ast.Node) int
+	visit = func(node ast.Node) int {
+		id := next
+		next++
+
+		label := ast.Kind(node)
+		if lit := node.TokenLiteral(); lit != "" {
+			label += "\n" + lit
+		}
+		fmt.Fprintf(&out, "\tn%d [label=%s];\n", id, quote(label))
+
+		for _, child := range ast.Children(node) {
+			childID := visit(child.Node)
+			fmt.Fprintf(&out, "\tn%d -> n%d [label=%s];\n", id, childID, quote(child.Role))
+		}
+		return id
+	}
+	visit(node)
+
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// atom returns the part of a node that is not a child node,
+// if it has one: the name, value or operator.
+func atom(node ast.Node) string {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		return node.Value
+	case *ast.IntegerLiteral:
+		return strconv.FormatInt(node.Value, 10)
+	case *ast.BigIntegerLiteral:
+		return node.Value.String()
+	case *ast.FloatLiteral:
+		return strconv.FormatFloat(node.Value, 'g', -1, 64)
+	case *ast.Boolean:
+		return strconv.FormatBool(node.Value)
+	case *ast.StringLiteral:
+		return strconv.Quote(node.Value)
+	case *ast.PrefixExpression:
+		return node.Operator
+	case *ast.InfixExpression:
+		return node.Operator
+	case *ast.AssignStatement:
+		return node.Operator
+	}
+	return ""
+}
+
+// quote returns s as a DOT double-quoted string.
+func quote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
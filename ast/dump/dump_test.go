@@ -0,0 +1,93 @@
+package dump
+
+import (
+	"github.com/juanfgarcia/gorilla/parser"
+	"testing"
+)
+
+func TestSExpr(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"-a * b + c;", `(Program
+  (ExpressionStatement
+    (InfixExpression +
+      (InfixExpression *
+        (PrefixExpression -
+          (Identifier a))
+        (Identifier b))
+      (Identifier c))))`},
+		{"if (x < 1) { true } else { false }", `(Program
+  (ExpressionStatement
+    (IfExpression
+      (InfixExpression <
+        (Identifier x)
+        (IntegerLiteral 1))
+      (BlockStatement
+        (ExpressionStatement
+          (Boolean true)))
+      (BlockStatement
+        (ExpressionStatement
+          (Boolean false))))))`},
+		{"fn(x) { x; }", `(Program
+  (ExpressionStatement
+    (FunctionLiteral
+      (Identifier x)
+      (BlockStatement
+        (ExpressionStatement
+          (Identifier x))))))`},
+		{"let x = 0; x += 1;", `(Program
+  (LetStatement
+    (Identifier x)
+    (IntegerLiteral 0))
+  (AssignStatement +=
+    (Identifier x)
+    (IntegerLiteral 1)))`},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(tt.input)
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors for %q: %v", tt.input, p.Errors())
+		}
+
+		got := SExpr(program)
+		if got != tt.expected {
+			t.Errorf("SExpr(%q) wrong,\nwant=%s\ngot= %s", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestDOT(t *testing.T) {
+	p := parser.New("a + 1;")
+	program := p.ParseProgram()
+
+	expected := `digraph ast {
+	node [shape=box];
+	n0 [label="Program\na"];
+	n1 [label="ExpressionStatement\na"];
+	n2 [label="InfixExpression\n+"];
+	n3 [label="Identifier\na"];
+	n2 -> n3 [label="left"];
+	n4 [label="IntegerLiteral\n1"];
+	n2 -> n4 [label="right"];
+	n1 -> n2 [label="expression"];
+	n0 -> n1 [label="statements"];
+}
+`
+
+	got := DOT(program)
+	if got != expected {
+		t.Errorf("DOT wrong,\nwant=%s\ngot= %s", expected, got)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	got := quote("a\"b\\c\nd")
+	expected := `"a\"b\\c\nd"`
+	if got != expected {
+		t.Errorf("quote wrong, want=%s got=%s", expected, got)
+	}
+}
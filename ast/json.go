@@ -0,0 +1,451 @@
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/juanfgarcia/gorilla/token"
+)
+
+// jsonNode is the serialized form of every node: its kind, the token
+// that starts it (with its position), an optional literal value or
+// operator, and its children in source order, each tagged with the
+// field it fills in its parent.
+type jsonNode struct {
+	Kind     string          `json:"kind"`
+	Token    *token.Token    `json:"token,omitempty"`
+	Value    json.RawMessage `json:"value,omitempty"`
+	Operator string          `json:"operator,omitempty"`
+	Children []jsonChild     `json:"children,omitempty"`
+}
+
+type jsonChild struct {
+	Role string    `json:"role"`
+	Node *jsonNode `json:"node"`
+}
+
+// MarshalJSON encodes the tree rooted at node.
+func MarshalJSON(node Node) ([]byte, error) {
+	jn, err := encodeNode(node)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jn)
+}
+
+// UnmarshalJSON decodes a tree previously encoded with MarshalJSON.
+func UnmarshalJSON(data []byte) (Node, error) {
+	var jn jsonNode
+	if err := json.Unmarshal(data, &jn); err != nil {
+		return nil, err
+	}
+	return decodeNode(&jn)
+}
+
+func encodeNode(node Node) (*jsonNode, error) {
+	jn := &jsonNode{Kind: Kind(node)}
+
+	var err error
+
+	switch node := node.(type) {
+	case *Program:
+		// A program has no token of its own.
+	case *LetStatement:
+		jn.Token = &node.Token
+	case *DeclareStatement:
+		jn.Token = &node.Token
+	case *AssignStatement:
+		jn.Token = &node.Token
+		jn.Operator = node.Operator
+	case *ReturnStatement:
+		jn.Token = &node.Token
+	case *ExpressionStatement:
+		jn.Token = &node.Token
+	case *BlockStatement:
+		jn.Token = &node.Token
+	case *Identifier:
+		jn.Token = &node.Token
+		jn.Value, err = json.Marshal(node.Value)
+	case *IntegerLiteral:
+		jn.Token = &node.Token
+		jn.Value, err = json.Marshal(node.Value)
+	case *BigIntegerLiteral:
+		jn.Token = &node.Token
+		jn.Value, err = json.Marshal(node.Value)
+	case *FloatLiteral:
+		jn.Token = &node.Token
+		jn.Value, err = json.Marshal(node.Value)
+	case *Boolean:
+		jn.Token = &node.Token
+		jn.Value, err = json.Marshal(node.Value)
+	case *PrefixExpression:
+		jn.Token = &node.Token
+		jn.Operator = node.Operator
+	case *InfixExpression:
+		jn.Token = &node.Token
+		jn.Operator = node.Operator
+	case *IfExpression:
+		jn.Token = &node.Token
+	case *FunctionLiteral:
+		jn.Token = &node.Token
+	case *MacroLiteral:
+		jn.Token = &node.Token
+	case *StringLiteral:
+		jn.Token = &node.Token
+		jn.Value, err = json.Marshal(node.Value)
+	case *ImportStatement:
+		jn.Token = &node.Token
+	case *ExportStatement:
+		jn.Token = &node.Token
+	case *MatchExpression:
+		jn.Token = &node.Token
+	case *MatchArm:
+		jn.Token = &node.Token
+	case *WildcardPattern:
+		jn.Token = &node.Token
+	case *ArrayPattern:
+		jn.Token = &node.Token
+	case *HashPattern:
+		jn.Token = &node.Token
+	case *WhileStatement:
+		jn.Token = &node.Token
+	case *ForStatement:
+		jn.Token = &node.Token
+	case *BreakStatement:
+		jn.Token = &node.Token
+	case *ContinueStatement:
+		jn.Token = &node.Token
+	default:
+		return nil, fmt.Errorf("ast: cannot encode node of type %T", node)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, child := range Children(node) {
+		c, err := encodeNode(child.Node)
+		if err != nil {
+			return nil, err
+		}
+		jn.Children = append(jn.Children, jsonChild{Role: child.Role, Node: c})
+	}
+
+	return jn, nil
+}
+
+func decodeNode(jn *jsonNode) (Node, error) {
+	if jn == nil {
+		return nil, fmt.Errorf("ast: missing node")
+	}
+
+	var tok token.Token
+	if jn.Token != nil {
+		tok = *jn.Token
+	}
+
+	switch jn.Kind {
+	case "Program":
+		program := &Program{Statements: []Statement{}}
+		err := jn.each("statements", func(n Node) error {
+			s, ok := n.(Statement)
+			if !ok {
+				return roleError(jn, "statements", n, "Statement")
+			}
+			program.Statements = append(program.Statements, s)
+			return nil
+		})
+		return program, err
+	case "LetStatement":
+		stmt := &LetStatement{Token: tok}
+		if err := jn.identifier("name", &stmt.Name); err != nil {
+			return nil, err
+		}
+		err := jn.expression("value", &stmt.Value)
+		return stmt, err
+	case "DeclareStatement":
+		stmt := &DeclareStatement{Token: tok}
+		if err := jn.identifier("name", &stmt.Name); err != nil {
+			return nil, err
+		}
+		err := jn.expression("value", &stmt.Value)
+		return stmt, err
+	case "AssignStatement":
+		stmt := &AssignStatement{Token: tok, Operator: jn.Operator}
+		if err := jn.identifier("name", &stmt.Name); err != nil {
+			return nil, err
+		}
+		err := jn.expression("value", &stmt.Value)
+		return stmt, err
+	case "ReturnStatement":
+		stmt := &ReturnStatement{Token: tok}
+		err := jn.expression("returnValue", &stmt.ReturnValue)
+		return stmt, err
+	case "ExpressionStatement":
+		stmt := &ExpressionStatement{Token: tok}
+		err := jn.expression("expression", &stmt.Expression)
+		return stmt, err
+	case "BlockStatement":
+		block := &BlockStatement{Token: tok, Statements: []Statement{}}
+		err := jn.each("statements", func(n Node) error {
+			s, ok := n.(Statement)
+			if !ok {
+				return roleError(jn, "statements", n, "Statement")
+			}
+			block.Statements = append(block.Statements, s)
+			return nil
+		})
+		return block, err
+	case "Identifier":
+		ident := &Identifier{Token: tok}
+		err := jn.value(&ident.Value)
+		return ident, err
+	case "IntegerLiteral":
+		lit := &IntegerLiteral{Token: tok}
+		err := jn.value(&lit.Value)
+		return lit, err
+	case "BigIntegerLiteral":
+		lit := &BigIntegerLiteral{Token: tok}
+		err := jn.value(&lit.Value)
+		return lit, err
+	case "FloatLiteral":
+		lit := &FloatLiteral{Token: tok}
+		err := jn.value(&lit.Value)
+		return lit, err
+	case "Boolean":
+		b := &Boolean{Token: tok}
+		err := jn.value(&b.Value)
+		return b, err
+	case "PrefixExpression":
+		exp := &PrefixExpression{Token: tok, Operator: jn.Operator}
+		err := jn.expression("right", &exp.Right)
+		return exp, err
+	case "InfixExpression":
+		exp := &InfixExpression{Token: tok, Operator: jn.Operator}
+		if err := jn.expression("left", &exp.Left); err != nil {
+			return nil, err
+		}
+		err := jn.expression("right", &exp.Right)
+		return exp, err
+	case "IfExpression":
+		exp := &IfExpression{Token: tok}
+		if err := jn.expression("condition", &exp.Condition); err != nil {
+			return nil, err
+		}
+		if err := jn.block("consequence", &exp.Consequence); err != nil {
+			return nil, err
+		}
+		err := jn.block("alternative", &exp.Alternative)
+		return exp, err
+	case "FunctionLiteral":
+		lit := &FunctionLiteral{Token: tok}
+		if err := jn.parameters(&lit.Parameters); err != nil {
+			return nil, err
+		}
+		err := jn.block("body", &lit.Body)
+		return lit, err
+	case "MacroLiteral":
+		lit := &MacroLiteral{Token: tok}
+		if err := jn.parameters(&lit.Parameters); err != nil {
+			return nil, err
+		}
+		err := jn.block("body", &lit.Body)
+		return lit, err
+	case "StringLiteral":
+		lit := &StringLiteral{Token: tok}
+		err := jn.value(&lit.Value)
+		return lit, err
+	case "ImportStatement":
+		stmt := &ImportStatement{Token: tok}
+		err := jn.each("path", func(n Node) error {
+			path, ok := n.(*StringLiteral)
+			if !ok {
+				return roleError(jn, "path", n, "StringLiteral")
+			}
+			stmt.Path = path
+			return nil
+		})
+		return stmt, err
+	case "ExportStatement":
+		stmt := &ExportStatement{Token: tok}
+		err := jn.each("statement", func(n Node) error {
+			let, ok := n.(*LetStatement)
+			if !ok {
+				return roleError(jn, "statement", n, "LetStatement")
+			}
+			stmt.Statement = let
+			return nil
+		})
+		return stmt, err
+	case "MatchExpression":
+		exp := &MatchExpression{Token: tok, Arms: []*MatchArm{}}
+		if err := jn.expression("subject", &exp.Subject); err != nil {
+			return nil, err
+		}
+		err := jn.each("arms", func(n Node) error {
+			arm, ok := n.(*MatchArm)
+			if !ok {
+				return roleError(jn, "arms", n, "MatchArm")
+			}
+			exp.Arms = append(exp.Arms, arm)
+			return nil
+		})
+		return exp, err
+	case "MatchArm":
+		arm := &MatchArm{Token: tok}
+		if err := jn.pattern("pattern", &arm.Pattern); err != nil {
+			return nil, err
+		}
+		if err := jn.expression("guard", &arm.Guard); err != nil {
+			return nil, err
+		}
+		err := jn.expression("body", &arm.Body)
+		return arm, err
+	case "WildcardPattern":
+		return &WildcardPattern{Token: tok}, nil
+	case "ArrayPattern":
+		pattern := &ArrayPattern{Token: tok, Elements: []Pattern{}}
+		err := jn.each("elements", func(n Node) error {
+			e, ok := n.(Pattern)
+			if !ok {
+				return roleError(jn, "elements", n, "Pattern")
+			}
+			pattern.Elements = append(pattern.Elements, e)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		err = jn.pattern("rest", &pattern.Rest)
+		return pattern, err
+	case "HashPattern":
+		pattern := &HashPattern{Token: tok, Keys: []Expression{}, Values: []Pattern{}}
+		err := jn.each("keys", func(n Node) error {
+			k, ok := n.(Expression)
+			if !ok {
+				return roleError(jn, "keys", n, "Expression")
+			}
+			pattern.Keys = append(pattern.Keys, k)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		err = jn.each("values", func(n Node) error {
+			v, ok := n.(Pattern)
+			if !ok {
+				return roleError(jn, "values", n, "Pattern")
+			}
+			pattern.Values = append(pattern.Values, v)
+			return nil
+		})
+		return pattern, err
+	case "WhileStatement":
+		stmt := &WhileStatement{Token: tok}
+		if err := jn.expression("condition", &stmt.Condition); err != nil {
+			return nil, err
+		}
+		err := jn.block("body", &stmt.Body)
+		return stmt, err
+	case "ForStatement":
+		stmt := &ForStatement{Token: tok}
+		if err := jn.identifier("variable", &stmt.Variable); err != nil {
+			return nil, err
+		}
+		if err := jn.expression("iterable", &stmt.Iterable); err != nil {
+			return nil, err
+		}
+		err := jn.block("body", &stmt.Body)
+		return stmt, err
+	case "BreakStatement":
+		return &BreakStatement{Token: tok}, nil
+	case "ContinueStatement":
+		return &ContinueStatement{Token: tok}, nil
+	default:
+		return nil, fmt.Errorf("ast: unknown node kind %q", jn.Kind)
+	}
+}
+
+// each decodes, in order, every child with the given role.
+func (jn *jsonNode) each(role string, f func(Node) error) error {
+	for _, c := range jn.Children {
+		if c.Role != role {
+			continue
+		}
+		n, err := decodeNode(c.Node)
+		if err != nil {
+			return err
+		}
+		if err := f(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (jn *jsonNode) expression(role string, dst *Expression) error {
+	return jn.each(role, func(n Node) error {
+		e, ok := n.(Expression)
+		if !ok {
+			return roleError(jn, role, n, "Expression")
+		}
+		*dst = e
+		return nil
+	})
+}
+
+func (jn *jsonNode) identifier(role string, dst **Identifier) error {
+	return jn.each(role, func(n Node) error {
+		i, ok := n.(*Identifier)
+		if !ok {
+			return roleError(jn, role, n, "Identifier")
+		}
+		*dst = i
+		return nil
+	})
+}
+
+func (jn *jsonNode) pattern(role string, dst *Pattern) error {
+	return jn.each(role, func(n Node) error {
+		p, ok := n.(Pattern)
+		if !ok {
+			return roleError(jn, role, n, "Pattern")
+		}
+		*dst = p
+		return nil
+	})
+}
+
+func (jn *jsonNode) block(role string, dst **BlockStatement) error {
+	return jn.each(role, func(n Node) error {
+		b, ok := n.(*BlockStatement)
+		if !ok {
+			return roleError(jn, role, n, "BlockStatement")
+		}
+		*dst = b
+		return nil
+	})
+}
+
+func (jn *jsonNode) parameters(dst *[]*Identifier) error {
+	*dst = []*Identifier{}
+	return jn.each("parameters", func(n Node) error {
+		i, ok := n.(*Identifier)
+		if !ok {
+			return roleError(jn, "parameters", n, "Identifier")
+		}
+		*dst = append(*dst, i)
+		return nil
+	})
+}
+
+func (jn *jsonNode) value(dst interface{}) error {
+	if jn.Value == nil {
+		return fmt.Errorf("ast: %s has no value", jn.Kind)
+	}
+	return json.Unmarshal(jn.Value, dst)
+}
+
+func roleError(parent *jsonNode, role string, got Node, want string) error {
+	return fmt.Errorf("ast: %s %s must be %s, got %T", parent.Kind, role, want, got)
+}
@@ -0,0 +1,98 @@
+package ast_test
+
+import (
+	"github.com/juanfgarcia/gorilla/ast"
+	"github.com/juanfgarcia/gorilla/parser"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	tests := []string{
+		`let x = 5;`,
+		`return 10;`,
+		`-a * b + !c;`,
+		`3 > 5 == false;`,
+		`if (x < y) { x } else { y }`,
+		`fn(x, y) { x + y; }`,
+		`fn() { }`,
+		`macro(a) { a; }`,
+		`let pi = 3.14; 2.5E-3 * 1e9;`,
+		`123456789012345678901234567890 + 1;`,
+		`while (x < 10) { break; } for (y in ys) { continue; }`,
+		`let x = 0; x = 1; x += 2 * y;`,
+		`x := fn(a) { a };`,
+		`match v { -1 => "neg", [h, ...t] if h > 0 => h, {"k": [_]} => 0, _ => v }`,
+		`import "lib/math"; export let x = 1; "a\tb";`,
+	}
+
+	for _, input := range tests {
+		p := parser.New(input)
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors for %q: %v", input, p.Errors())
+		}
+
+		data, err := ast.MarshalJSON(program)
+		if err != nil {
+			t.Fatalf("MarshalJSON(%q) failed: %v", input, err)
+		}
+
+		decoded, err := ast.UnmarshalJSON(data)
+		if err != nil {
+			t.Fatalf("UnmarshalJSON(%q) failed: %v", input, err)
+		}
+
+		if decoded.String() != program.String() {
+			t.Errorf("round trip of %q changed the program, want=%q got=%q", input, program.String(), decoded.String())
+		}
+
+		if !reflect.DeepEqual(decoded, program) {
+			t.Errorf("round trip of %q is not deeply equal to the original", input)
+		}
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	p := parser.New(`1 + 2;`)
+	program := p.ParseProgram()
+
+	data, err := ast.MarshalJSON(program)
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %v", err)
+	}
+
+	want := `{"kind":"Program","children":[` +
+		`{"role":"statements","node":{"kind":"ExpressionStatement","token":{"type":"INT","literal":"1","line":1,"column":1},"children":[` +
+		`{"role":"expression","node":{"kind":"InfixExpression","token":{"type":"PLUS","literal":"+","line":1,"column":3},"operator":"+","children":[` +
+		`{"role":"left","node":{"kind":"IntegerLiteral","token":{"type":"INT","literal":"1","line":1,"column":1},"value":1}},` +
+		`{"role":"right","node":{"kind":"IntegerLiteral","token":{"type":"INT","literal":"2","line":1,"column":5},"value":2}}]}}]}}]}`
+
+	if string(data) != want {
+		t.Errorf("MarshalJSON wrong,\nwant=%s\ngot= %s", want, data)
+	}
+}
+
+func TestJSONDecodeErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{`{"kind":"Nope"}`, `unknown node kind "Nope"`},
+		{`{"kind":"Identifier","token":{"type":"IDENTIFIER","literal":"x"}}`, "Identifier has no value"},
+		{`{"kind":"Identifier","token":{"type":"WHAT","literal":"x"},"value":"x"}`, `unknown token type "WHAT"`},
+		{`{"kind":"Program","children":[{"role":"statements","node":{"kind":"Boolean","value":true}}]}`, "Program statements must be Statement"},
+	}
+
+	for _, tt := range tests {
+		_, err := ast.UnmarshalJSON([]byte(tt.input))
+		if err == nil {
+			t.Errorf("expected error decoding %s", tt.input)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("error decoding %s should mention %q, got=%q", tt.input, tt.err, err)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package ast
+
+// ModifierFunc receives a node and returns the node that should replace it.
+type ModifierFunc func(Node) Node
+
+// Modify walks the tree rooted at node depth-first, replacing every child
+// with the result of calling modifier on it, and finally returns
+// modifier(node). Nil children are left untouched.
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch node := node.(type) {
+	case *Program:
+		for i, statement := range node.Statements {
+			node.Statements[i], _ = Modify(statement, modifier).(Statement)
+		}
+	case *ExpressionStatement:
+		node.Expression = modifyExpression(node.Expression, modifier)
+	case *LetStatement:
+		node.Value = modifyExpression(node.Value, modifier)
+	case *DeclareStatement:
+		node.Value = modifyExpression(node.Value, modifier)
+	case *AssignStatement:
+		node.Value = modifyExpression(node.Value, modifier)
+	case *ReturnStatement:
+		node.ReturnValue = modifyExpression(node.ReturnValue, modifier)
+	case *BlockStatement:
+		for i, statement := range node.Statements {
+			node.Statements[i], _ = Modify(statement, modifier).(Statement)
+		}
+	case *PrefixExpression:
+		node.Right = modifyExpression(node.Right, modifier)
+	case *InfixExpression:
+		node.Left = modifyExpression(node.Left, modifier)
+		node.Right = modifyExpression(node.Right, modifier)
+	case *IfExpression:
+		node.Condition = modifyExpression(node.Condition, modifier)
+		node.Consequence = modifyBlock(node.Consequence, modifier)
+		node.Alternative = modifyBlock(node.Alternative, modifier)
+	case *FunctionLiteral:
+		for i, param := range node.Parameters {
+			node.Parameters[i], _ = Modify(param, modifier).(*Identifier)
+		}
+		node.Body = modifyBlock(node.Body, modifier)
+	case *MacroLiteral:
+		for i, param := range node.Parameters {
+			node.Parameters[i], _ = Modify(param, modifier).(*Identifier)
+		}
+		node.Body = modifyBlock(node.Body, modifier)
+	case *ExportStatement:
+		if node.Statement != nil {
+			node.Statement, _ = Modify(node.Statement, modifier).(*LetStatement)
+		}
+	case *WhileStatement:
+		node.Condition = modifyExpression(node.Condition, modifier)
+		node.Body = modifyBlock(node.Body, modifier)
+	case *MatchExpression:
+		node.Subject = modifyExpression(node.Subject, modifier)
+		for i, arm := range node.Arms {
+			node.Arms[i], _ = Modify(arm, modifier).(*MatchArm)
+		}
+	case *MatchArm:
+		node.Pattern = modifyPattern(node.Pattern, modifier)
+		node.Guard = modifyExpression(node.Guard, modifier)
+		node.Body = modifyExpression(node.Body, modifier)
+	case *ArrayPattern:
+		for i, e := range node.Elements {
+			node.Elements[i] = modifyPattern(e, modifier)
+		}
+		node.Rest = modifyPattern(node.Rest, modifier)
+	case *HashPattern:
+		for i, k := range node.Keys {
+			node.Keys[i] = modifyExpression(k, modifier)
+		}
+		for i, v := range node.Values {
+			node.Values[i] = modifyPattern(v, modifier)
+		}
+	case *ForStatement:
+		if node.Variable != nil {
+			node.Variable, _ = Modify(node.Variable, modifier).(*Identifier)
+		}
+		node.Iterable = modifyExpression(node.Iterable, modifier)
+		node.Body = modifyBlock(node.Body, modifier)
+	}
+
+	return modifier(node)
+}
+
+func modifyExpression(exp Expression, modifier ModifierFunc) Expression {
+	if exp == nil {
+		return nil
+	}
+	modified, _ := Modify(exp, modifier).(Expression)
+	return modified
+}
+
+func modifyBlock(block *BlockStatement, modifier ModifierFunc) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	modified, _ := Modify(block, modifier).(*BlockStatement)
+	return modified
+}
+
+func modifyPattern(pattern Pattern, modifier ModifierFunc) Pattern {
+	if pattern == nil {
+		return nil
+	}
+	modified, _ := Modify(pattern, modifier).(Pattern)
+	return modified
+}
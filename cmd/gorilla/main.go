@@ -0,0 +1,109 @@
+// Command gorilla is the command line front end of the gorilla language.
+//
+// Usage:
+//
+//	gorilla parse [--format=string|json|dot|sexpr] [--json] [file]
+//	gorilla lsp
+//
+// parse reads a program from file, or from standard input when no file is
+// given, and prints its syntax tree in the requested format; --json is
+// shorthand for --format=json.
+//
+// lsp runs a language server speaking JSON-RPC over standard input and
+// output, for use from editors.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/juanfgarcia/gorilla/ast"
+	"github.com/juanfgarcia/gorilla/ast/dump"
+	"github.com/juanfgarcia/gorilla/lsp"
+	"github.com/juanfgarcia/gorilla/parser"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+
+	switch os.Args[1] {
+	case "parse":
+		err = parse(os.Args[2:])
+	case "lsp":
+		err = lsp.NewServer(os.Stdin, os.Stdout).Run()
+	default:
+		usage()
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gorilla: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: gorilla parse [--format=string|json|dot|sexpr] [--json] [file]")
+	fmt.Fprintln(os.Stderr, "       gorilla lsp")
+	os.Exit(2)
+}
+
+func parse(args []string) error {
+	flags := flag.NewFlagSet("parse", flag.ExitOnError)
+	format := flags.String("format", "string", "output `format`: string, json, dot or sexpr")
+	asJSON := flags.Bool("json", false, "shorthand for --format=json")
+	flags.Parse(args)
+
+	if *asJSON {
+		*format = "json"
+	}
+
+	src, err := readSource(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	p := parser.New(src)
+	program := p.ParseProgram()
+
+	if errors := p.Errors(); len(errors) != 0 {
+		for _, msg := range errors {
+			fmt.Fprintln(os.Stderr, msg)
+		}
+		return fmt.Errorf("%d parse errors", len(errors))
+	}
+
+	switch *format {
+	case "string":
+		fmt.Println(program.String())
+	case "json":
+		data, err := ast.MarshalJSON(program)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "dot":
+		fmt.Print(dump.DOT(program))
+	case "sexpr":
+		fmt.Println(dump.SExpr(program))
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	return nil
+}
+
+// readSource returns the contents of path, or of standard input when
+// path is empty.
+func readSource(path string) (string, error) {
+	if path == "" {
+		src, err := io.ReadAll(os.Stdin)
+		return string(src), err
+	}
+	src, err := os.ReadFile(path)
+	return string(src), err
+}
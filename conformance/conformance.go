@@ -0,0 +1,610 @@
+// Package conformance holds programs together with what every stage of
+// gorilla must make of them: the tokens the lexer produces, the syntax
+// tree the parser builds, printed by dump.SExpr, and the value the
+// program evaluates to.
+//
+// The cases are shared so that any new lexer, parser, optimizer or
+// evaluator can be checked against the same table as the existing ones.
+package conformance
+
+import "github.com/juanfgarcia/gorilla/token"
+
+// Token is a token without its position.
+type Token struct {
+	Type    token.TokenType
+	Literal string
+}
+
+type Case struct {
+	Name  string
+	Input string
+
+	// Tokens are the tokens of Input, ending with EOF.
+	Tokens []Token
+
+	// SExpr is the syntax tree of Input as printed by dump.SExpr.
+	SExpr string
+
+	// Value is the printed value of the last statement of Input, or
+	// empty when the program does not evaluate to a constant.
+	Value string
+}
+
+var Cases = []Case{
+	{
+		Name:  "less than",
+		Input: "1 < 2;",
+		Tokens: []Token{
+			{token.INT, "1"},
+			{token.LT, "<"},
+			{token.INT, "2"},
+			{token.SEMICOLON, ";"},
+			{token.EOF, ""},
+		},
+		SExpr: `(Program
+  (ExpressionStatement
+    (InfixExpression <
+      (IntegerLiteral 1)
+      (IntegerLiteral 2))))`,
+		Value: "true",
+	},
+	{
+		Name:  "greater than",
+		Input: "1 > 2;",
+		Tokens: []Token{
+			{token.INT, "1"},
+			{token.GT, ">"},
+			{token.INT, "2"},
+			{token.SEMICOLON, ";"},
+			{token.EOF, ""},
+		},
+		SExpr: `(Program
+  (ExpressionStatement
+    (InfixExpression >
+      (IntegerLiteral 1)
+      (IntegerLiteral 2))))`,
+		Value: "false",
+	},
+	{
+		Name:  "less or equal and greater or equal",
+		Input: "2 <= 2 == 1 >= 2;",
+		Tokens: []Token{
+			{token.INT, "2"},
+			{token.LTE, "<="},
+			{token.INT, "2"},
+			{token.EQUALS, "=="},
+			{token.INT, "1"},
+			{token.GTE, ">="},
+			{token.INT, "2"},
+			{token.SEMICOLON, ";"},
+			{token.EOF, ""},
+		},
+		SExpr: `(Program
+  (ExpressionStatement
+    (InfixExpression ==
+      (InfixExpression <=
+        (IntegerLiteral 2)
+        (IntegerLiteral 2))
+      (InfixExpression >=
+        (IntegerLiteral 1)
+        (IntegerLiteral 2)))))`,
+		Value: "false",
+	},
+	{
+		Name:  "relational operators without spaces",
+		Input: "a<b>c",
+		Tokens: []Token{
+			{token.IDENTIFIER, "a"},
+			{token.LT, "<"},
+			{token.IDENTIFIER, "b"},
+			{token.GT, ">"},
+			{token.IDENTIFIER, "c"},
+			{token.EOF, ""},
+		},
+		SExpr: `(Program
+  (ExpressionStatement
+    (InfixExpression >
+      (InfixExpression <
+        (Identifier a)
+        (Identifier b))
+      (Identifier c))))`,
+	},
+	{
+		Name:  "arithmetic precedence",
+		Input: "1 + 2 * 3 - -4 / 2;",
+		Tokens: []Token{
+			{token.INT, "1"},
+			{token.PLUS, "+"},
+			{token.INT, "2"},
+			{token.ASTERISK, "*"},
+			{token.INT, "3"},
+			{token.MINUS, "-"},
+			{token.MINUS, "-"},
+			{token.INT, "4"},
+			{token.SLASH, "/"},
+			{token.INT, "2"},
+			{token.SEMICOLON, ";"},
+			{token.EOF, ""},
+		},
+		SExpr: `(Program
+  (ExpressionStatement
+    (InfixExpression -
+      (InfixExpression +
+        (IntegerLiteral 1)
+        (InfixExpression *
+          (IntegerLiteral 2)
+          (IntegerLiteral 3)))
+      (InfixExpression /
+        (PrefixExpression -
+          (IntegerLiteral 4))
+        (IntegerLiteral 2)))))`,
+		Value: "9",
+	},
+	{
+		Name:  "grouping",
+		Input: "(1 + 2) * 3;",
+		Tokens: []Token{
+			{token.LPAREN, "("},
+			{token.INT, "1"},
+			{token.PLUS, "+"},
+			{token.INT, "2"},
+			{token.RPAREN, ")"},
+			{token.ASTERISK, "*"},
+			{token.INT, "3"},
+			{token.SEMICOLON, ";"},
+			{token.EOF, ""},
+		},
+		SExpr: `(Program
+  (ExpressionStatement
+    (InfixExpression *
+      (InfixExpression +
+        (IntegerLiteral 1)
+        (IntegerLiteral 2))
+      (IntegerLiteral 3))))`,
+		Value: "9",
+	},
+	{
+		Name:  "integer division and modulo",
+		Input: "-7 / 2 + -7 % 3;",
+		Tokens: []Token{
+			{token.MINUS, "-"},
+			{token.INT, "7"},
+			{token.SLASH, "/"},
+			{token.INT, "2"},
+			{token.PLUS, "+"},
+			{token.MINUS, "-"},
+			{token.INT, "7"},
+			{token.PERCENT, "%"},
+			{token.INT, "3"},
+			{token.SEMICOLON, ";"},
+			{token.EOF, ""},
+		},
+		SExpr: `(Program
+  (ExpressionStatement
+    (InfixExpression +
+      (InfixExpression /
+        (PrefixExpression -
+          (IntegerLiteral 7))
+        (IntegerLiteral 2))
+      (InfixExpression %
+        (PrefixExpression -
+          (IntegerLiteral 7))
+        (IntegerLiteral 3)))))`,
+		Value: "-4",
+	},
+	{
+		Name:  "float promotion",
+		Input: "7 / 2.0;",
+		Tokens: []Token{
+			{token.INT, "7"},
+			{token.SLASH, "/"},
+			{token.FLOAT, "2.0"},
+			{token.SEMICOLON, ";"},
+			{token.EOF, ""},
+		},
+		SExpr: `(Program
+  (ExpressionStatement
+    (InfixExpression /
+      (IntegerLiteral 7)
+      (FloatLiteral 2))))`,
+		Value: "3.5",
+	},
+	{
+		Name:  "integer overflow",
+		Input: "9223372036854775807 + 1;",
+		Tokens: []Token{
+			{token.INT, "9223372036854775807"},
+			{token.PLUS, "+"},
+			{token.INT, "1"},
+			{token.SEMICOLON, ";"},
+			{token.EOF, ""},
+		},
+		SExpr: `(Program
+  (ExpressionStatement
+    (InfixExpression +
+      (IntegerLiteral 9223372036854775807)
+      (IntegerLiteral 1))))`,
+		Value: "9223372036854775808",
+	},
+	{
+		Name:  "number bases",
+		Input: "0xff + 0b1 + 1_000;",
+		Tokens: []Token{
+			{token.INT, "0xff"},
+			{token.PLUS, "+"},
+			{token.INT, "0b1"},
+			{token.PLUS, "+"},
+			{token.INT, "1_000"},
+			{token.SEMICOLON, ";"},
+			{token.EOF, ""},
+		},
+		SExpr: `(Program
+  (ExpressionStatement
+    (InfixExpression +
+      (InfixExpression +
+        (IntegerLiteral 255)
+        (IntegerLiteral 1))
+      (IntegerLiteral 1000))))`,
+		Value: "1256",
+	},
+	{
+		Name:  "bitwise operators",
+		Input: "~0 & 0xf0 | 1 << 2 ^ 3;",
+		Tokens: []Token{
+			{token.TILDE, "~"},
+			{token.INT, "0"},
+			{token.AMPERSAND, "&"},
+			{token.INT, "0xf0"},
+			{token.PIPE, "|"},
+			{token.INT, "1"},
+			{token.SHL, "<<"},
+			{token.INT, "2"},
+			{token.CARET, "^"},
+			{token.INT, "3"},
+			{token.SEMICOLON, ";"},
+			{token.EOF, ""},
+		},
+		SExpr: `(Program
+  (ExpressionStatement
+    (InfixExpression ^
+      (InfixExpression |
+        (InfixExpression &
+          (PrefixExpression ~
+            (IntegerLiteral 0))
+          (IntegerLiteral 240))
+        (InfixExpression <<
+          (IntegerLiteral 1)
+          (IntegerLiteral 2)))
+      (IntegerLiteral 3))))`,
+		Value: "247",
+	},
+	{
+		Name:  "logical operators",
+		Input: "!(1 < 2) || 3 > 2 && true;",
+		Tokens: []Token{
+			{token.BANG, "!"},
+			{token.LPAREN, "("},
+			{token.INT, "1"},
+			{token.LT, "<"},
+			{token.INT, "2"},
+			{token.RPAREN, ")"},
+			{token.OR, "||"},
+			{token.INT, "3"},
+			{token.GT, ">"},
+			{token.INT, "2"},
+			{token.AND, "&&"},
+			{token.TRUE, "true"},
+			{token.SEMICOLON, ";"},
+			{token.EOF, ""},
+		},
+		SExpr: `(Program
+  (ExpressionStatement
+    (InfixExpression ||
+      (PrefixExpression !
+        (InfixExpression <
+          (IntegerLiteral 1)
+          (IntegerLiteral 2)))
+      (InfixExpression &&
+        (InfixExpression >
+          (IntegerLiteral 3)
+          (IntegerLiteral 2))
+        (Boolean true)))))`,
+		Value: "true",
+	},
+	{
+		Name:  "if else",
+		Input: "if (1 > 2) { 10 } else { 20 }",
+		Tokens: []Token{
+			{token.IF, "if"},
+			{token.LPAREN, "("},
+			{token.INT, "1"},
+			{token.GT, ">"},
+			{token.INT, "2"},
+			{token.RPAREN, ")"},
+			{token.LBRACE, "{"},
+			{token.INT, "10"},
+			{token.RBRACE, "}"},
+			{token.ELSE, "else"},
+			{token.LBRACE, "{"},
+			{token.INT, "20"},
+			{token.RBRACE, "}"},
+			{token.EOF, ""},
+		},
+		SExpr: `(Program
+  (ExpressionStatement
+    (IfExpression
+      (InfixExpression >
+        (IntegerLiteral 1)
+        (IntegerLiteral 2))
+      (BlockStatement
+        (ExpressionStatement
+          (IntegerLiteral 10)))
+      (BlockStatement
+        (ExpressionStatement
+          (IntegerLiteral 20))))))`,
+		Value: "20",
+	},
+	{
+		Name:  "let and function",
+		Input: `let max = fn(a, b) { if (a >= b) { a } else { b } };`,
+		Tokens: []Token{
+			{token.LET, "let"},
+			{token.IDENTIFIER, "max"},
+			{token.ASSIGN, "="},
+			{token.FUNCTION, "fn"},
+			{token.LPAREN, "("},
+			{token.IDENTIFIER, "a"},
+			{token.COMMA, ","},
+			{token.IDENTIFIER, "b"},
+			{token.RPAREN, ")"},
+			{token.LBRACE, "{"},
+			{token.IF, "if"},
+			{token.LPAREN, "("},
+			{token.IDENTIFIER, "a"},
+			{token.GTE, ">="},
+			{token.IDENTIFIER, "b"},
+			{token.RPAREN, ")"},
+			{token.LBRACE, "{"},
+			{token.IDENTIFIER, "a"},
+			{token.RBRACE, "}"},
+			{token.ELSE, "else"},
+			{token.LBRACE, "{"},
+			{token.IDENTIFIER, "b"},
+			{token.RBRACE, "}"},
+			{token.RBRACE, "}"},
+			{token.SEMICOLON, ";"},
+			{token.EOF, ""},
+		},
+		SExpr: `(Program
+  (LetStatement
+    (Identifier max)
+    (FunctionLiteral
+      (Identifier a)
+      (Identifier b)
+      (BlockStatement
+        (ExpressionStatement
+          (IfExpression
+            (InfixExpression >=
+              (Identifier a)
+              (Identifier b))
+            (BlockStatement
+              (ExpressionStatement
+                (Identifier a)))
+            (BlockStatement
+              (ExpressionStatement
+                (Identifier b)))))))))`,
+	},
+	{
+		Name:  "strings",
+		Input: `"a" == "b";`,
+		Tokens: []Token{
+			{token.STRING, `"a"`},
+			{token.EQUALS, "=="},
+			{token.STRING, `"b"`},
+			{token.SEMICOLON, ";"},
+			{token.EOF, ""},
+		},
+		SExpr: `(Program
+  (ExpressionStatement
+    (InfixExpression ==
+      (StringLiteral "a")
+      (StringLiteral "b"))))`,
+	},
+	{
+		Name:  "while loop",
+		Input: "while (x < 3) { x; }",
+		Tokens: []Token{
+			{token.WHILE, "while"},
+			{token.LPAREN, "("},
+			{token.IDENTIFIER, "x"},
+			{token.LT, "<"},
+			{token.INT, "3"},
+			{token.RPAREN, ")"},
+			{token.LBRACE, "{"},
+			{token.IDENTIFIER, "x"},
+			{token.SEMICOLON, ";"},
+			{token.RBRACE, "}"},
+			{token.EOF, ""},
+		},
+		SExpr: `(Program
+  (WhileStatement
+    (InfixExpression <
+      (Identifier x)
+      (IntegerLiteral 3))
+    (BlockStatement
+      (ExpressionStatement
+        (Identifier x)))))`,
+	},
+	{
+		Name:  "for loop with break and continue",
+		Input: "for (x in y) { if (x) { continue; } break; }",
+		Tokens: []Token{
+			{token.FOR, "for"},
+			{token.LPAREN, "("},
+			{token.IDENTIFIER, "x"},
+			{token.IN, "in"},
+			{token.IDENTIFIER, "y"},
+			{token.RPAREN, ")"},
+			{token.LBRACE, "{"},
+			{token.IF, "if"},
+			{token.LPAREN, "("},
+			{token.IDENTIFIER, "x"},
+			{token.RPAREN, ")"},
+			{token.LBRACE, "{"},
+			{token.CONTINUE, "continue"},
+			{token.SEMICOLON, ";"},
+			{token.RBRACE, "}"},
+			{token.BREAK, "break"},
+			{token.SEMICOLON, ";"},
+			{token.RBRACE, "}"},
+			{token.EOF, ""},
+		},
+		SExpr: `(Program
+  (ForStatement
+    (Identifier x)
+    (Identifier y)
+    (BlockStatement
+      (ExpressionStatement
+        (IfExpression
+          (Identifier x)
+          (BlockStatement
+            (ContinueStatement))))
+      (BreakStatement))))`,
+	},
+	{
+		Name:  "assignment and compound assignment",
+		Input: "let x = 1; x = x * 2; x += 3;",
+		Tokens: []Token{
+			{token.LET, "let"},
+			{token.IDENTIFIER, "x"},
+			{token.ASSIGN, "="},
+			{token.INT, "1"},
+			{token.SEMICOLON, ";"},
+			{token.IDENTIFIER, "x"},
+			{token.ASSIGN, "="},
+			{token.IDENTIFIER, "x"},
+			{token.ASTERISK, "*"},
+			{token.INT, "2"},
+			{token.SEMICOLON, ";"},
+			{token.IDENTIFIER, "x"},
+			{token.PLUSASSIGN, "+="},
+			{token.INT, "3"},
+			{token.SEMICOLON, ";"},
+			{token.EOF, ""},
+		},
+		SExpr: `(Program
+  (LetStatement
+    (Identifier x)
+    (IntegerLiteral 1))
+  (AssignStatement =
+    (Identifier x)
+    (InfixExpression *
+      (Identifier x)
+      (IntegerLiteral 2)))
+  (AssignStatement +=
+    (Identifier x)
+    (IntegerLiteral 3)))`,
+	},
+	{
+		Name:  "short declaration",
+		Input: "x := 1; if (x) { x := x + 1; }",
+		Tokens: []Token{
+			{token.IDENTIFIER, "x"},
+			{token.DECLARE, ":="},
+			{token.INT, "1"},
+			{token.SEMICOLON, ";"},
+			{token.IF, "if"},
+			{token.LPAREN, "("},
+			{token.IDENTIFIER, "x"},
+			{token.RPAREN, ")"},
+			{token.LBRACE, "{"},
+			{token.IDENTIFIER, "x"},
+			{token.DECLARE, ":="},
+			{token.IDENTIFIER, "x"},
+			{token.PLUS, "+"},
+			{token.INT, "1"},
+			{token.SEMICOLON, ";"},
+			{token.RBRACE, "}"},
+			{token.EOF, ""},
+		},
+		SExpr: `(Program
+  (DeclareStatement
+    (Identifier x)
+    (IntegerLiteral 1))
+  (ExpressionStatement
+    (IfExpression
+      (Identifier x)
+      (BlockStatement
+        (DeclareStatement
+          (Identifier x)
+          (InfixExpression +
+            (Identifier x)
+            (IntegerLiteral 1)))))))`,
+	},
+	{
+		Name:  "match expression",
+		Input: "match xs { [] => 0, [h, ...t] if h > 0 => h, {\"k\": _} => -1, _ => 1 };",
+		Tokens: []Token{
+			{token.MATCH, "match"},
+			{token.IDENTIFIER, "xs"},
+			{token.LBRACE, "{"},
+			{token.LBRACKET, "["},
+			{token.RBRACKET, "]"},
+			{token.FATARROW, "=>"},
+			{token.INT, "0"},
+			{token.COMMA, ","},
+			{token.LBRACKET, "["},
+			{token.IDENTIFIER, "h"},
+			{token.COMMA, ","},
+			{token.ELLIPSIS, "..."},
+			{token.IDENTIFIER, "t"},
+			{token.RBRACKET, "]"},
+			{token.IF, "if"},
+			{token.IDENTIFIER, "h"},
+			{token.GT, ">"},
+			{token.INT, "0"},
+			{token.FATARROW, "=>"},
+			{token.IDENTIFIER, "h"},
+			{token.COMMA, ","},
+			{token.LBRACE, "{"},
+			{token.STRING, "\"k\""},
+			{token.COLON, ":"},
+			{token.UNDERSCORE, "_"},
+			{token.RBRACE, "}"},
+			{token.FATARROW, "=>"},
+			{token.MINUS, "-"},
+			{token.INT, "1"},
+			{token.COMMA, ","},
+			{token.UNDERSCORE, "_"},
+			{token.FATARROW, "=>"},
+			{token.INT, "1"},
+			{token.RBRACE, "}"},
+			{token.SEMICOLON, ";"},
+			{token.EOF, ""},
+		},
+		SExpr: `(Program
+  (ExpressionStatement
+    (MatchExpression
+      (Identifier xs)
+      (MatchArm
+        (ArrayPattern)
+        (IntegerLiteral 0))
+      (MatchArm
+        (ArrayPattern
+          (Identifier h)
+          (Identifier t))
+        (InfixExpression >
+          (Identifier h)
+          (IntegerLiteral 0))
+        (Identifier h))
+      (MatchArm
+        (HashPattern
+          (StringLiteral "k")
+          (WildcardPattern))
+        (PrefixExpression -
+          (IntegerLiteral 1)))
+      (MatchArm
+        (WildcardPattern)
+        (IntegerLiteral 1)))))`,
+	},
+}
@@ -0,0 +1,73 @@
+package conformance
+
+import (
+	"testing"
+
+	"github.com/juanfgarcia/gorilla/ast/dump"
+	"github.com/juanfgarcia/gorilla/lexer"
+	"github.com/juanfgarcia/gorilla/optimize"
+	"github.com/juanfgarcia/gorilla/parser"
+	"github.com/juanfgarcia/gorilla/token"
+)
+
+func TestTokens(t *testing.T) {
+	for _, tt := range Cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			l := lexer.New(tt.Input)
+
+			for i, want := range tt.Tokens {
+				got := l.NextToken()
+				if got.Typ != want.Type || got.Literal != want.Literal {
+					t.Fatalf("[%d] want=%s %q got=%s %q", i, want.Type, want.Literal, got.Typ, got.Literal)
+				}
+			}
+
+			if got := l.NextToken(); got.Typ != token.EOF {
+				t.Errorf("unexpected token after the end, got=%s %q", got.Typ, got.Literal)
+			}
+		})
+	}
+}
+
+func TestSExpr(t *testing.T) {
+	for _, tt := range Cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			p := parser.New(tt.Input)
+			program := p.ParseProgram()
+			if len(p.Errors()) != 0 {
+				t.Fatalf("parser errors: %v", p.Errors())
+			}
+
+			if got := dump.SExpr(program); got != tt.SExpr {
+				t.Errorf("wrong tree,\nwant=%s\ngot= %s", tt.SExpr, got)
+			}
+		})
+	}
+}
+
+// TestValues checks the constant folder, the only stage that computes
+// values so far, against the cases with a constant value.
+func TestValues(t *testing.T) {
+	for _, tt := range Cases {
+		if tt.Value == "" {
+			continue
+		}
+
+		t.Run(tt.Name, func(t *testing.T) {
+			p := parser.New(tt.Input)
+			program := p.ParseProgram()
+			if len(p.Errors()) != 0 {
+				t.Fatalf("parser errors: %v", p.Errors())
+			}
+
+			folded, diagnostics := optimize.Fold(program)
+			if len(diagnostics) != 0 {
+				t.Fatalf("unexpected diagnostics: %v", diagnostics)
+			}
+
+			if got := folded.String(); got != tt.Value {
+				t.Errorf("wrong value, want=%q got=%q", tt.Value, got)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package lexer
 
 import (
+	"strings"
+
 	"github.com/juanfgarcia/gorilla/token"
 )
 
@@ -9,6 +11,19 @@ type Lexer struct {
 	position int
 	start    int
 	tokens   chan token.Token
+
+	// width is how far the last read advanced, zero at the end
+	// of the input, so that backup can undo it.
+	width int
+
+	// last is the last token handed to the client.
+	last token.Token
+
+	// line and column locate the start of the next token,
+	// scanned is how far into the input they have been counted.
+	line    int
+	column  int
+	scanned int
 }
 
 // LexState is a function that represent a state in the lexer,
@@ -21,24 +36,35 @@ func New(input string) *Lexer {
 		position: 0,
 		start:    0,
 		tokens:   make(chan token.Token),
+		line:     1,
+		column:   1,
 	}
 	go lex.run()
 	return lex
 }
 
-// next returns the next char in the input
+// next returns the next char in the input, or 0 at the end of it
 func (lex *Lexer) read() byte {
 	if lex.position >= len(lex.input) {
+		lex.width = 0
 		return 0
 	}
 	ch := lex.input[lex.position]
 	lex.position++
+	lex.width = 1
 	return ch
 }
 
-// backup steps back one position
+// atEOF reports whether the last read hit the end of the input,
+// as opposed to reading a NUL byte.
+func (lex *Lexer) atEOF() bool {
+	return lex.width == 0
+}
+
+// backup steps back over the last read, it can be called only once
+// per call of read
 func (lex *Lexer) backup() {
-	lex.position--
+	lex.position -= lex.width
 }
 
 // peek returns the next char but does not consume it
@@ -50,15 +76,38 @@ func (lex *Lexer) peek() byte {
 
 // emit passes a token to the client
 func (lex *Lexer) emit(typ token.TokenType) {
-	lex.tokens <- token.Token{typ, lex.input[lex.start:lex.position]}
+	lex.locate()
+	lex.tokens <- token.Token{
+		Typ:     typ,
+		Literal: lex.input[lex.start:lex.position],
+		Line:    lex.line,
+		Column:  lex.column,
+	}
 	lex.start = lex.position
 }
 
+// locate updates line and column to point at the start of the
+// current token.
+func (lex *Lexer) locate() {
+	for ; lex.scanned < lex.start; lex.scanned++ {
+		if lex.input[lex.scanned] == '\n' {
+			lex.line++
+			lex.column = 1
+		} else {
+			lex.column++
+		}
+	}
+}
+
 // NextToken is the public interface from the lexer
 // to the client, it return the tokens concurrently
-// as they are read.
+// as they are read. Once the input is exhausted it keeps
+// returning the EOF token.
 func (lex *Lexer) NextToken() token.Token {
-	return <-lex.tokens
+	if tok, ok := <-lex.tokens; ok {
+		lex.last = tok
+	}
+	return lex.last
 }
 
 func (lex *Lexer) run() {
@@ -69,10 +118,6 @@ func (lex *Lexer) run() {
 }
 
 func (lex *Lexer) ignoreWhiteSpaces() {
-	if lex.position >= len(lex.input) {
-		return
-	}
-
 	for ch := lex.read(); isSpace(ch); {
 		ch = lex.read()
 	}
@@ -89,8 +134,11 @@ func startState(lex *Lexer) LexState {
 	switch ch {
 	case 0:
 		{
-			lex.emit(token.EOF)
-			return nil
+			if lex.atEOF() {
+				lex.emit(token.EOF)
+				return nil
+			}
+			lex.emit(token.ILLEGAL)
 		}
 	case '!':
 		{
@@ -102,9 +150,53 @@ func startState(lex *Lexer) LexState {
 			}
 		}
 	case '>':
-		lex.emit(token.LT)
+		{
+			switch lex.peek() {
+			case '=':
+				lex.read()
+				lex.emit(token.GTE)
+			case '>':
+				lex.read()
+				lex.emit(token.SHR)
+			default:
+				lex.emit(token.GT)
+			}
+		}
 	case '<':
-		lex.emit(token.GT)
+		{
+			switch lex.peek() {
+			case '=':
+				lex.read()
+				lex.emit(token.LTE)
+			case '<':
+				lex.read()
+				lex.emit(token.SHL)
+			default:
+				lex.emit(token.LT)
+			}
+		}
+	case '&':
+		{
+			if lex.peek() == '&' {
+				lex.read()
+				lex.emit(token.AND)
+			} else {
+				lex.emit(token.AMPERSAND)
+			}
+		}
+	case '|':
+		{
+			if lex.peek() == '|' {
+				lex.read()
+				lex.emit(token.OR)
+			} else {
+				lex.emit(token.PIPE)
+			}
+		}
+	case '^':
+		lex.emit(token.CARET)
+	case '~':
+		lex.emit(token.TILDE)
 	case '(':
 		lex.emit(token.LPAREN)
 	case ')':
@@ -113,16 +205,36 @@ func startState(lex *Lexer) LexState {
 		lex.emit(token.LBRACE)
 	case '}':
 		lex.emit(token.RBRACE)
+	case '[':
+		lex.emit(token.LBRACKET)
+	case ']':
+		lex.emit(token.RBRACKET)
+	case '_':
+		lex.emit(token.UNDERSCORE)
+	case '.':
+		{
+			if strings.HasPrefix(lex.input[lex.position:], "..") {
+				lex.read()
+				lex.read()
+				lex.emit(token.ELLIPSIS)
+			} else {
+				lex.emit(token.ILLEGAL)
+			}
+		}
 	case ',':
 		lex.emit(token.COMMA)
 	case ';':
 		lex.emit(token.SEMICOLON)
 	case '=':
 		{
-			if lex.peek() == '=' {
+			switch lex.peek() {
+			case '=':
 				lex.read()
 				lex.emit(token.EQUALS)
-			} else {
+			case '>':
+				lex.read()
+				lex.emit(token.FATARROW)
+			default:
 				lex.emit(token.ASSIGN)
 			}
 		}
@@ -130,26 +242,55 @@ func startState(lex *Lexer) LexState {
 		{
 			if lex.peek() == '=' {
 				lex.read()
-				lex.emit(token.ASSIGN)
+				lex.emit(token.DECLARE)
 			} else {
 				lex.emit(token.COLON)
 			}
 		}
 	case '-':
 		{
-			if lex.peek() == '>' {
+			switch lex.peek() {
+			case '>':
 				lex.read()
 				lex.emit(token.RIGHTARROW)
-			} else {
+			case '=':
+				lex.read()
+				lex.emit(token.MINUSASSIGN)
+			default:
 				lex.emit(token.MINUS)
 			}
 		}
+	case '"':
+		return stringState(lex)
 	case '+':
-		lex.emit(token.PLUS)
+		{
+			if lex.peek() == '=' {
+				lex.read()
+				lex.emit(token.PLUSASSIGN)
+			} else {
+				lex.emit(token.PLUS)
+			}
+		}
 	case '/':
-		lex.emit(token.SLASH)
+		{
+			if lex.peek() == '=' {
+				lex.read()
+				lex.emit(token.SLASHASSIGN)
+			} else {
+				lex.emit(token.SLASH)
+			}
+		}
+	case '%':
+		lex.emit(token.PERCENT)
 	case '*':
-		lex.emit(token.ASTERISK)
+		{
+			if lex.peek() == '=' {
+				lex.read()
+				lex.emit(token.ASTERISKASSIGN)
+			} else {
+				lex.emit(token.ASTERISK)
+			}
+		}
 	default:
 		{
 			if isLetter(ch) {
@@ -160,9 +301,10 @@ func startState(lex *Lexer) LexState {
 				lex.backup()
 				return IntState(lex)
 			}
+			lex.emit(token.ILLEGAL)
 		}
 	}
-	return startState(lex)
+	return startState
 }
 
 func identifierState(lex *Lexer) LexState {
@@ -175,15 +317,110 @@ func identifierState(lex *Lexer) LexState {
 	return startState
 }
 
+// stringState lexes a double quoted string whose opening quote has
+// already been read. The token keeps the quotes and escapes as written;
+// a string left open at the end of the input is ILLEGAL.
+func stringState(lex *Lexer) LexState {
+	for {
+		if lex.position >= len(lex.input) {
+			lex.emit(token.ILLEGAL)
+			return startState
+		}
+		switch lex.read() {
+		case '\\':
+			lex.read()
+		case '"':
+			lex.emit(token.STRING)
+			return startState
+		}
+	}
+}
+
+// IntState lexes a decimal integer, or one in base 16, 2 or 8 when it
+// starts with 0x, 0b or 0o. Digits may be separated by underscores.
+// The literal is not validated here: a prefixed literal takes every
+// letter and digit that follows, so that 0b2 or 1_000_ reach the parser
+// as a single INT token that it reports as malformed.
 func IntState(lex *Lexer) LexState {
-	for ch := lex.read(); isNumber(ch); {
-		ch = lex.read()
+	if lex.basePrefixFollows() {
+		lex.read()
+		lex.read()
+		for ch := lex.read(); isLetter(ch) || isNumber(ch) || ch == '_'; {
+			ch = lex.read()
+		}
+		lex.backup()
+		lex.emit(token.INT)
+		return startState
+	}
+
+	lex.acceptDigits()
+	if lex.fractionFollows() || lex.exponentFollows() {
+		return FloatState(lex)
 	}
-	lex.backup()
 	lex.emit(token.INT)
 	return startState
 }
 
+// FloatState lexes the fraction and exponent of a number whose
+// integer part has already been read, as in 3.14, 1e9 or 2.5E-3.
+func FloatState(lex *Lexer) LexState {
+	if lex.fractionFollows() {
+		lex.read()
+		lex.acceptDigits()
+	}
+	if lex.exponentFollows() {
+		lex.read()
+		if ch := lex.peek(); ch == '+' || ch == '-' {
+			lex.read()
+		}
+		lex.acceptDigits()
+	}
+	lex.emit(token.FLOAT)
+	return startState
+}
+
+// acceptDigits consumes a run of decimal digits and underscores.
+func (lex *Lexer) acceptDigits() {
+	for ch := lex.read(); isNumber(ch) || ch == '_'; {
+		ch = lex.read()
+	}
+	lex.backup()
+}
+
+// basePrefixFollows reports whether the input continues with 0x, 0b
+// or 0o, in either case.
+func (lex *Lexer) basePrefixFollows() bool {
+	rest := lex.input[lex.position:]
+	if len(rest) < 2 || rest[0] != '0' {
+		return false
+	}
+	switch rest[1] {
+	case 'x', 'X', 'b', 'B', 'o', 'O':
+		return true
+	}
+	return false
+}
+
+// fractionFollows reports whether the input continues with a dot
+// and a digit.
+func (lex *Lexer) fractionFollows() bool {
+	rest := lex.input[lex.position:]
+	return len(rest) > 1 && rest[0] == '.' && isNumber(rest[1])
+}
+
+// exponentFollows reports whether the input continues with an
+// exponent: e or E, an optional sign and a digit.
+func (lex *Lexer) exponentFollows() bool {
+	rest := lex.input[lex.position:]
+	if len(rest) < 2 || (rest[0] != 'e' && rest[0] != 'E') {
+		return false
+	}
+	if (rest[1] == '+' || rest[1] == '-') && len(rest) > 2 {
+		return isNumber(rest[2])
+	}
+	return isNumber(rest[1])
+}
+
 func isLetter(ch byte) bool {
 	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
 }
@@ -193,5 +430,5 @@ func isNumber(ch byte) bool {
 }
 
 func isSpace(ch byte) bool {
-	return ch == ' ' || ch == '\t' || ch == '\n'
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
 }
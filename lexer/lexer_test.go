@@ -2,6 +2,7 @@ package lexer
 
 import (
 	"github.com/juanfgarcia/gorilla/token"
+	"strings"
 	"testing"
 )
 
@@ -36,7 +37,7 @@ func TestLexer(t *testing.T) {
 		tests := []tokenTest{
 			{token.LET, "let"},
 			{token.IDENTIFIER, "a"},
-			{token.ASSIGN, ":="},
+			{token.DECLARE, ":="},
 			{token.INT, "3"},
 			{token.SEMICOLON, ";"},
 			{token.EOF, ""},
@@ -79,6 +80,28 @@ func TestLexer(t *testing.T) {
 
 	})
 
+	t.Run("Strings and imports", func(t *testing.T) {
+		input := `import "lib/math";
+export let s = "a \"quoted\" word";
+"open`
+
+		want := []tokenTest{
+			{token.IMPORT, "import"},
+			{token.STRING, `"lib/math"`},
+			{token.SEMICOLON, ";"},
+			{token.EXPORT, "export"},
+			{token.LET, "let"},
+			{token.IDENTIFIER, "s"},
+			{token.ASSIGN, "="},
+			{token.STRING, `"a \"quoted\" word"`},
+			{token.SEMICOLON, ";"},
+			{token.ILLEGAL, `"open`},
+			{token.EOF, ""},
+		}
+
+		LexAssert(t, input, want)
+	})
+
 	t.Run("Arithmetic expressions", func(t *testing.T) {
 		input := `2+3/5*4-;`
 
@@ -97,4 +120,279 @@ func TestLexer(t *testing.T) {
 
 		LexAssert(t, input, want)
 	})
+
+	t.Run("Comparison and logical operators", func(t *testing.T) {
+		input := `x < y > a <= b >= c % d && e || f & |`
+
+		want := []tokenTest{
+			{token.IDENTIFIER, "x"},
+			{token.LT, "<"},
+			{token.IDENTIFIER, "y"},
+			{token.GT, ">"},
+			{token.IDENTIFIER, "a"},
+			{token.LTE, "<="},
+			{token.IDENTIFIER, "b"},
+			{token.GTE, ">="},
+			{token.IDENTIFIER, "c"},
+			{token.PERCENT, "%"},
+			{token.IDENTIFIER, "d"},
+			{token.AND, "&&"},
+			{token.IDENTIFIER, "e"},
+			{token.OR, "||"},
+			{token.IDENTIFIER, "f"},
+			{token.AMPERSAND, "&"},
+			{token.PIPE, "|"},
+			{token.EOF, ""},
+		}
+
+		LexAssert(t, input, want)
+	})
+
+	t.Run("Bitwise operators", func(t *testing.T) {
+		input := `a & b | c ^ ~d << 2 >> 1 &&|| <<= >>=`
+
+		want := []tokenTest{
+			{token.IDENTIFIER, "a"},
+			{token.AMPERSAND, "&"},
+			{token.IDENTIFIER, "b"},
+			{token.PIPE, "|"},
+			{token.IDENTIFIER, "c"},
+			{token.CARET, "^"},
+			{token.TILDE, "~"},
+			{token.IDENTIFIER, "d"},
+			{token.SHL, "<<"},
+			{token.INT, "2"},
+			{token.SHR, ">>"},
+			{token.INT, "1"},
+			{token.AND, "&&"},
+			{token.OR, "||"},
+			{token.SHL, "<<"},
+			{token.ASSIGN, "="},
+			{token.SHR, ">>"},
+			{token.ASSIGN, "="},
+			{token.EOF, ""},
+		}
+
+		LexAssert(t, input, want)
+	})
+
+	t.Run("Assignment operators", func(t *testing.T) {
+		input := `x = 1; x += 2; x -= 3; x *= 4; x /= 5; f -> x`
+
+		want := []tokenTest{
+			{token.IDENTIFIER, "x"},
+			{token.ASSIGN, "="},
+			{token.INT, "1"},
+			{token.SEMICOLON, ";"},
+			{token.IDENTIFIER, "x"},
+			{token.PLUSASSIGN, "+="},
+			{token.INT, "2"},
+			{token.SEMICOLON, ";"},
+			{token.IDENTIFIER, "x"},
+			{token.MINUSASSIGN, "-="},
+			{token.INT, "3"},
+			{token.SEMICOLON, ";"},
+			{token.IDENTIFIER, "x"},
+			{token.ASTERISKASSIGN, "*="},
+			{token.INT, "4"},
+			{token.SEMICOLON, ";"},
+			{token.IDENTIFIER, "x"},
+			{token.SLASHASSIGN, "/="},
+			{token.INT, "5"},
+			{token.SEMICOLON, ";"},
+			{token.IDENTIFIER, "f"},
+			{token.RIGHTARROW, "->"},
+			{token.IDENTIFIER, "x"},
+			{token.EOF, ""},
+		}
+
+		LexAssert(t, input, want)
+	})
+
+	t.Run("Loops", func(t *testing.T) {
+		input := `while for in break continue inside`
+
+		want := []tokenTest{
+			{token.WHILE, "while"},
+			{token.FOR, "for"},
+			{token.IN, "in"},
+			{token.BREAK, "break"},
+			{token.CONTINUE, "continue"},
+			{token.IDENTIFIER, "inside"},
+			{token.EOF, ""},
+		}
+
+		LexAssert(t, input, want)
+	})
+
+	t.Run("Match", func(t *testing.T) {
+		input := `match v { [h, ...t] => h, _ => 0 } .. =>`
+
+		want := []tokenTest{
+			{token.MATCH, "match"},
+			{token.IDENTIFIER, "v"},
+			{token.LBRACE, "{"},
+			{token.LBRACKET, "["},
+			{token.IDENTIFIER, "h"},
+			{token.COMMA, ","},
+			{token.ELLIPSIS, "..."},
+			{token.IDENTIFIER, "t"},
+			{token.RBRACKET, "]"},
+			{token.FATARROW, "=>"},
+			{token.IDENTIFIER, "h"},
+			{token.COMMA, ","},
+			{token.UNDERSCORE, "_"},
+			{token.FATARROW, "=>"},
+			{token.INT, "0"},
+			{token.RBRACE, "}"},
+			{token.ILLEGAL, "."},
+			{token.ILLEGAL, "."},
+			{token.FATARROW, "=>"},
+			{token.EOF, ""},
+		}
+
+		LexAssert(t, input, want)
+	})
+
+	t.Run("Floats", func(t *testing.T) {
+		input := `3.14 1e9 2.5E-3 7e+2 3. 1e x.5`
+
+		want := []tokenTest{
+			{token.FLOAT, "3.14"},
+			{token.FLOAT, "1e9"},
+			{token.FLOAT, "2.5E-3"},
+			{token.FLOAT, "7e+2"},
+			{token.INT, "3"},
+			{token.ILLEGAL, "."},
+			{token.INT, "1"},
+			{token.IDENTIFIER, "e"},
+			{token.IDENTIFIER, "x"},
+			{token.ILLEGAL, "."},
+			{token.INT, "5"},
+			{token.EOF, ""},
+		}
+
+		LexAssert(t, input, want)
+	})
+
+	t.Run("Number bases", func(t *testing.T) {
+		input := `0xFF 0b1010 0O17 1_000_000 0b2 1_000_ 0x 0xff+1`
+
+		want := []tokenTest{
+			{token.INT, "0xFF"},
+			{token.INT, "0b1010"},
+			{token.INT, "0O17"},
+			{token.INT, "1_000_000"},
+			{token.INT, "0b2"},
+			{token.INT, "1_000_"},
+			{token.INT, "0x"},
+			{token.INT, "0xff"},
+			{token.PLUS, "+"},
+			{token.INT, "1"},
+			{token.EOF, ""},
+		}
+
+		LexAssert(t, input, want)
+	})
+}
+
+func TestPositions(t *testing.T) {
+	input := `let a = 3;
+  fn(x) {
+	x }`
+
+	want := []token.Token{
+		{Typ: token.LET, Literal: "let", Line: 1, Column: 1},
+		{Typ: token.IDENTIFIER, Literal: "a", Line: 1, Column: 5},
+		{Typ: token.ASSIGN, Literal: "=", Line: 1, Column: 7},
+		{Typ: token.INT, Literal: "3", Line: 1, Column: 9},
+		{Typ: token.SEMICOLON, Literal: ";", Line: 1, Column: 10},
+		{Typ: token.FUNCTION, Literal: "fn", Line: 2, Column: 3},
+		{Typ: token.LPAREN, Literal: "(", Line: 2, Column: 5},
+		{Typ: token.IDENTIFIER, Literal: "x", Line: 2, Column: 6},
+		{Typ: token.RPAREN, Literal: ")", Line: 2, Column: 7},
+		{Typ: token.LBRACE, Literal: "{", Line: 2, Column: 9},
+		{Typ: token.IDENTIFIER, Literal: "x", Line: 3, Column: 2},
+		{Typ: token.RBRACE, Literal: "}", Line: 3, Column: 4},
+		{Typ: token.EOF, Literal: "", Line: 3, Column: 5},
+	}
+
+	lexer := New(input)
+
+	for i, tt := range want {
+		got := lexer.NextToken()
+
+		if got != tt {
+			t.Errorf("[%d]Got %+v but want %+v", i, got, tt)
+		}
+	}
+}
+
+func TestEndOfInput(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []tokenTest
+	}{
+		{"ab", []tokenTest{{token.IDENTIFIER, "ab"}, {token.EOF, ""}}},
+		{"12", []tokenTest{{token.INT, "12"}, {token.EOF, ""}}},
+		{"x  \r\n", []tokenTest{{token.IDENTIFIER, "x"}, {token.EOF, ""}}},
+		{"a @ b", []tokenTest{{token.IDENTIFIER, "a"}, {token.ILLEGAL, "@"}, {token.IDENTIFIER, "b"}, {token.EOF, ""}}},
+		{"a\x00b", []tokenTest{{token.IDENTIFIER, "a"}, {token.ILLEGAL, "\x00"}, {token.IDENTIFIER, "b"}, {token.EOF, ""}}},
+		// The EOF token is repeated once the input is exhausted.
+		{"", []tokenTest{{token.EOF, ""}, {token.EOF, ""}, {token.EOF, ""}}},
+	}
+
+	for _, tt := range tests {
+		LexAssert(t, tt.input, tt.want)
+	}
+}
+
+// FuzzLexer checks that the lexer ends every input with an EOF token
+// after at most one token per byte, and that every token is the slice
+// of the input found at its position.
+func FuzzLexer(f *testing.F) {
+	f.Add(`let a := 3;`)
+	f.Add(`fn add(x : Int, y: Int) -> Int { return x + y; }`)
+	f.Add(`import "lib"; export let s = "a\"b";`)
+	f.Add("a\x00\r\n@")
+
+	f.Fuzz(func(t *testing.T, input string) {
+		lineStarts := []int{0}
+		for i := 0; i < len(input); i++ {
+			if input[i] == '\n' {
+				lineStarts = append(lineStarts, i+1)
+			}
+		}
+
+		lexer := New(input)
+		end := 0
+
+		for i := 0; i <= len(input); i++ {
+			tok := lexer.NextToken()
+
+			if tok.Line < 1 || tok.Line > len(lineStarts) {
+				t.Fatalf("%+v has an invalid line", tok)
+			}
+			offset := lineStarts[tok.Line-1] + tok.Column - 1
+			if offset < end || offset+len(tok.Literal) > len(input) {
+				t.Fatalf("%+v is out of place, previous token ended at %d", tok, end)
+			}
+			if !strings.HasPrefix(input[offset:], tok.Literal) {
+				t.Fatalf("%+v does not match the input at %d", tok, offset)
+			}
+
+			if tok.Typ == token.EOF {
+				if offset != len(input) {
+					t.Fatalf("EOF at %d, before the end of the input", offset)
+				}
+				return
+			}
+			if tok.Literal == "" {
+				t.Fatalf("%+v is empty", tok)
+			}
+			end = offset + len(tok.Literal)
+		}
+
+		t.Fatalf("no EOF after %d tokens", len(input)+1)
+	})
 }
@@ -0,0 +1,280 @@
+package lsp
+
+import (
+	"strings"
+
+	"github.com/juanfgarcia/gorilla/ast"
+	"github.com/juanfgarcia/gorilla/parser"
+	"github.com/juanfgarcia/gorilla/token"
+)
+
+// document is an open text document together with its parse.
+type document struct {
+	uri     string
+	program *ast.Program
+	errors  []parser.Error
+
+	// lines is the text split at line breaks, to convert the byte
+	// columns of tokens into UTF-16 offsets.
+	lines []string
+
+	// defs maps every identifier whose binding could be resolved
+	// to the identifier that declares it.
+	defs map[*ast.Identifier]*ast.Identifier
+}
+
+func newDocument(uri, text string) *document {
+	p := parser.New(text)
+
+	doc := &document{
+		uri:     uri,
+		program: p.ParseProgram(),
+		errors:  p.Diagnostics(),
+		lines:   strings.Split(text, "\n"),
+		defs:    map[*ast.Identifier]*ast.Identifier{},
+	}
+	doc.resolve(doc.program, newScope(nil))
+
+	return doc
+}
+
+func (doc *document) diagnostics() []diagnostic {
+	diagnostics := []diagnostic{}
+	for _, err := range doc.errors {
+		diagnostics = append(diagnostics, diagnostic{
+			Range:    doc.tokenRange(err.Token),
+			Severity: severityError,
+			Source:   "gorilla",
+			Message:  err.Msg,
+		})
+	}
+	return diagnostics
+}
+
+// symbols lists the top level let statements, exported or not, and
+// short declarations.
+func (doc *document) symbols() []documentSymbol {
+	symbols := []documentSymbol{}
+	for _, stmt := range doc.program.Statements {
+		if export, ok := stmt.(*ast.ExportStatement); ok {
+			stmt = export.Statement
+		}
+
+		var start token.Token
+		var ident *ast.Identifier
+		var value ast.Expression
+
+		switch stmt := stmt.(type) {
+		case *ast.LetStatement:
+			start, ident, value = stmt.Token, stmt.Name, stmt.Value
+		case *ast.DeclareStatement:
+			if stmt.Name != nil {
+				start = stmt.Name.Token
+			}
+			ident, value = stmt.Name, stmt.Value
+		}
+		if ident == nil {
+			continue
+		}
+
+		kind := symbolKindVariable
+		if _, ok := value.(*ast.FunctionLiteral); ok {
+			kind = symbolKindFunction
+		}
+
+		name := doc.tokenRange(ident.Token)
+		symbols = append(symbols, documentSymbol{
+			Name:           ident.Value,
+			Kind:           kind,
+			Range:          rangeLSP{Start: doc.tokenRange(start).Start, End: name.End},
+			SelectionRange: name,
+		})
+	}
+	return symbols
+}
+
+// nodeAt returns the innermost node whose token covers pos, or nil.
+func (doc *document) nodeAt(pos position) ast.Node {
+	var found ast.Node
+
+	var visit func(node ast.Node)
+	visit = func(node ast.Node) {
+		if tok, ok := ast.StartToken(node); ok && contains(doc.tokenRange(tok), pos) {
+			found = node
+		}
+		for _, child := range ast.Children(node) {
+			visit(child.Node)
+		}
+	}
+	visit(doc.program)
+
+	return found
+}
+
+// definition returns the identifier declaring the one at pos.
+func (doc *document) definition(pos position) (*ast.Identifier, bool) {
+	ident, ok := doc.nodeAt(pos).(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+	def, ok := doc.defs[ident]
+	return def, ok
+}
+
+type scope struct {
+	names map[string]*ast.Identifier
+	outer *scope
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{names: map[string]*ast.Identifier{}, outer: outer}
+}
+
+func (s *scope) lookup(name string) (*ast.Identifier, bool) {
+	for ; s != nil; s = s.outer {
+		if ident, ok := s.names[name]; ok {
+			return ident, true
+		}
+	}
+	return nil, false
+}
+
+func (doc *document) declare(s *scope, ident *ast.Identifier) {
+	s.names[ident.Value] = ident
+	doc.defs[ident] = ident
+}
+
+// resolve binds the identifiers under node to their declarations. A let
+// binding is visible from its own value onwards, so recursive functions
+// resolve, while a short declaration is visible only after its value.
+// Every block and function body opens a new scope, the variable of a
+// for loop is visible in the loop body only, and the names bound by a
+// match pattern in the guard and body of their arm only.
+func (doc *document) resolve(node ast.Node, s *scope) {
+	switch node := node.(type) {
+	case *ast.Program:
+		for _, stmt := range node.Statements {
+			doc.resolve(stmt, s)
+		}
+	case *ast.BlockStatement:
+		inner := newScope(s)
+		for _, stmt := range node.Statements {
+			doc.resolve(stmt, inner)
+		}
+	case *ast.LetStatement:
+		if node.Name != nil {
+			doc.declare(s, node.Name)
+		}
+		if node.Value != nil {
+			doc.resolve(node.Value, s)
+		}
+	case *ast.DeclareStatement:
+		// Unlike let, the value cannot refer to the name it declares.
+		if node.Value != nil {
+			doc.resolve(node.Value, s)
+		}
+		if node.Name != nil {
+			doc.declare(s, node.Name)
+		}
+	case *ast.FunctionLiteral:
+		doc.resolveFunction(node.Parameters, node.Body, s)
+	case *ast.MacroLiteral:
+		doc.resolveFunction(node.Parameters, node.Body, s)
+	case *ast.ForStatement:
+		if node.Iterable != nil {
+			doc.resolve(node.Iterable, s)
+		}
+		inner := newScope(s)
+		if node.Variable != nil {
+			doc.declare(inner, node.Variable)
+		}
+		if node.Body != nil {
+			doc.resolve(node.Body, inner)
+		}
+	case *ast.MatchArm:
+		inner := newScope(s)
+		doc.bind(node.Pattern, inner)
+		if node.Guard != nil {
+			doc.resolve(node.Guard, inner)
+		}
+		if node.Body != nil {
+			doc.resolve(node.Body, inner)
+		}
+	case *ast.Identifier:
+		if def, ok := s.lookup(node.Value); ok {
+			doc.defs[node] = def
+		}
+	default:
+		for _, child := range ast.Children(node) {
+			doc.resolve(child.Node, s)
+		}
+	}
+}
+
+// bind declares the names bound by a match pattern.
+func (doc *document) bind(pattern ast.Node, s *scope) {
+	if ident, ok := pattern.(*ast.Identifier); ok {
+		doc.declare(s, ident)
+		return
+	}
+	for _, child := range ast.Children(pattern) {
+		doc.bind(child.Node, s)
+	}
+}
+
+func (doc *document) resolveFunction(params []*ast.Identifier, body *ast.BlockStatement, s *scope) {
+	inner := newScope(s)
+	for _, param := range params {
+		doc.declare(inner, param)
+	}
+	if body != nil {
+		doc.resolve(body, inner)
+	}
+}
+
+// tokenRange converts the one based position of tok, whose column
+// counts bytes, into an LSP range spanning its literal, which may run
+// over several lines.
+func (doc *document) tokenRange(tok token.Token) rangeLSP {
+	start := doc.position(tok.Line-1, tok.Column-1)
+
+	end := start
+	if i := strings.LastIndexByte(tok.Literal, '\n'); i >= 0 {
+		end.Line += strings.Count(tok.Literal, "\n")
+		end.Character = utf16Len(tok.Literal[i+1:])
+	} else {
+		end.Character += utf16Len(tok.Literal)
+	}
+
+	return rangeLSP{Start: start, End: end}
+}
+
+// position converts a zero based line and byte offset into that line
+// into an LSP position, which counts UTF-16 code units.
+func (doc *document) position(line, offset int) position {
+	if line < 0 || line >= len(doc.lines) || offset > len(doc.lines[line]) {
+		return position{Line: line, Character: offset}
+	}
+	return position{Line: line, Character: utf16Len(doc.lines[line][:offset])}
+}
+
+// utf16Len returns the number of UTF-16 code units encoding s.
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+func contains(r rangeLSP, pos position) bool {
+	return !before(pos, r.Start) && before(pos, r.End)
+}
+
+func before(a, b position) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Character < b.Character
+}
@@ -0,0 +1,248 @@
+// Package lsp implements a Language Server Protocol server for gorilla.
+//
+// The server speaks JSON-RPC with Content-Length framing, keeps every
+// open document fully synchronized and answers from its parse: it
+// publishes parser diagnostics, lists top level let statements as
+// document symbols, shows the kind of the syntax node under the cursor
+// on hover and jumps from identifiers to the declaration they refer to.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/juanfgarcia/gorilla/ast"
+)
+
+// Server is a language server reading requests from in and writing
+// responses and notifications to out.
+type Server struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	docs     map[string]*document
+	shutdown bool
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:   bufio.NewReader(in),
+		out:  out,
+		docs: map[string]*document{},
+	}
+}
+
+// Run serves requests until the client sends exit or closes its end of
+// the connection. Exiting without a previous shutdown request is
+// reported as an error.
+func (s *Server) Run() error {
+	for {
+		body, err := readMessage(s.in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			if err := s.replyError(json.RawMessage("null"), codeParseError, err.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("lsp: exit without shutdown")
+			}
+			return nil
+		}
+
+		result, err := s.dispatch(&msg)
+
+		if msg.ID == nil {
+			// Notifications get no response, not even on failure.
+			continue
+		}
+
+		if err != nil {
+			rerr, ok := err.(*responseError)
+			if !ok {
+				rerr = &responseError{Code: codeInternalError, Message: err.Error()}
+			}
+			err = s.replyError(msg.ID, rerr.Code, rerr.Message)
+		} else {
+			err = s.reply(msg.ID, result)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// dispatch runs the handler for msg, turning a panic into an error so
+// that a bug in one request does not bring the editor's server down.
+func (s *Server) dispatch(msg *message) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("lsp: %s failed: %v", msg.Method, r)
+		}
+	}()
+
+	if s.shutdown {
+		return nil, &responseError{Code: codeInvalidRequest, Message: "server is shut down"}
+	}
+
+	switch msg.Method {
+	case "initialize":
+		return s.initialize()
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.update(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		// With full synchronization the last change holds the whole text.
+		text := params.ContentChanges[len(params.ContentChanges)-1].Text
+		return nil, s.update(params.TextDocument.URI, text)
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return nil, s.publishDiagnostics(params.TextDocument.URI, []diagnostic{})
+	case "textDocument/documentSymbol":
+		var params documentSymbolParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		doc, err := s.document(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return doc.symbols(), nil
+	case "textDocument/hover":
+		var params textDocumentPositionParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.hover(params)
+	case "textDocument/definition":
+		var params textDocumentPositionParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.definition(params)
+	default:
+		return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %q not found", msg.Method)}
+	}
+}
+
+func (s *Server) initialize() (interface{}, error) {
+	return initializeResult{
+		Capabilities: serverCapabilities{
+			TextDocumentSync:       textDocumentSyncFull,
+			DocumentSymbolProvider: true,
+			HoverProvider:          true,
+			DefinitionProvider:     true,
+		},
+		ServerInfo: serverInfo{Name: "gorilla"},
+	}, nil
+}
+
+// update reparses the document at uri and publishes its diagnostics.
+func (s *Server) update(uri, text string) error {
+	doc := newDocument(uri, text)
+	s.docs[uri] = doc
+	return s.publishDiagnostics(uri, doc.diagnostics())
+}
+
+func (s *Server) document(uri string) (*document, error) {
+	doc, ok := s.docs[uri]
+	if !ok {
+		return nil, &responseError{Code: codeInvalidParams, Message: fmt.Sprintf("document %q is not open", uri)}
+	}
+	return doc, nil
+}
+
+func (s *Server) hover(params textDocumentPositionParams) (interface{}, error) {
+	doc, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	node := doc.nodeAt(params.Position)
+	if node == nil {
+		return nil, nil
+	}
+
+	tok, _ := ast.StartToken(node)
+	return hover{
+		Contents: markupContent{Kind: "plaintext", Value: ast.Kind(node)},
+		Range:    doc.tokenRange(tok),
+	}, nil
+}
+
+func (s *Server) definition(params textDocumentPositionParams) (interface{}, error) {
+	doc, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	def, ok := doc.definition(params.Position)
+	if !ok {
+		return nil, nil
+	}
+
+	return location{URI: doc.uri, Range: doc.tokenRange(def.Token)}, nil
+}
+
+func (s *Server) publishDiagnostics(uri string, diagnostics []diagnostic) error {
+	return writeMessage(s.out, notification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params:  publishDiagnosticsParams{URI: uri, Diagnostics: diagnostics},
+	})
+}
+
+func (s *Server) reply(id json.RawMessage, result interface{}) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return writeMessage(s.out, response{JSONRPC: "2.0", ID: id, Result: data})
+}
+
+func (s *Server) replyError(id json.RawMessage, code int, msg string) error {
+	return writeMessage(s.out, errorResponse{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error:   &responseError{Code: code, Message: msg},
+	})
+}
+
+func unmarshalParams(msg *message, v interface{}) error {
+	if err := json.Unmarshal(msg.Params, v); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,410 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// client drives a Server running in the same process through a pair of
+// pipes, the way an editor would over stdio.
+type client struct {
+	t      testing.TB
+	w      io.WriteCloser
+	msgs   chan map[string]json.RawMessage
+	done   chan error
+	nextID int
+
+	// notifications received while waiting for a response.
+	pending []map[string]json.RawMessage
+}
+
+func newClient(t testing.TB) *client {
+	t.Helper()
+
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+
+	c := &client{
+		t:    t,
+		w:    clientOut,
+		msgs: make(chan map[string]json.RawMessage),
+		done: make(chan error, 1),
+	}
+
+	go func() {
+		err := NewServer(serverIn, serverOut).Run()
+		serverOut.Close()
+		c.done <- err
+	}()
+
+	go func() {
+		r := bufio.NewReader(clientIn)
+		for {
+			body, err := readMessage(r)
+			if err != nil {
+				close(c.msgs)
+				return
+			}
+			var msg map[string]json.RawMessage
+			if err := json.Unmarshal(body, &msg); err != nil {
+				t.Errorf("server sent invalid JSON %q: %v", body, err)
+			}
+			c.msgs <- msg
+		}
+	}()
+
+	c.request("initialize", map[string]interface{}{}, nil)
+	c.notify("initialized", map[string]interface{}{})
+
+	return c
+}
+
+func (c *client) notify(method string, params interface{}) {
+	c.t.Helper()
+
+	err := writeMessage(c.w, map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		c.t.Fatalf("sending %s failed: %v", method, err)
+	}
+}
+
+// request sends a request and decodes its result into result, failing
+// the test on an error response.
+func (c *client) request(method string, params interface{}, result interface{}) {
+	c.t.Helper()
+
+	if rerr := c.call(method, params, result); rerr != nil {
+		c.t.Fatalf("%s failed: %v", method, rerr)
+	}
+}
+
+func (c *client) call(method string, params interface{}, result interface{}) *responseError {
+	c.t.Helper()
+
+	c.nextID++
+	id, _ := json.Marshal(c.nextID)
+
+	err := writeMessage(c.w, map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      c.nextID,
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		c.t.Fatalf("sending %s failed: %v", method, err)
+	}
+
+	for msg := range c.msgs {
+		if _, ok := msg["id"]; !ok {
+			c.pending = append(c.pending, msg)
+			continue
+		}
+		if string(msg["id"]) != string(id) {
+			c.t.Fatalf("response to %s has id %s, want %s", method, msg["id"], id)
+		}
+		if raw, ok := msg["error"]; ok {
+			var rerr responseError
+			json.Unmarshal(raw, &rerr)
+			return &rerr
+		}
+		if result != nil {
+			if err := json.Unmarshal(msg["result"], result); err != nil {
+				c.t.Fatalf("decoding result of %s failed: %v", method, err)
+			}
+		}
+		return nil
+	}
+
+	c.t.Fatalf("connection closed waiting for %s", method)
+	return nil
+}
+
+// diagnostics returns the next diagnostics published by the server.
+func (c *client) diagnostics() publishDiagnosticsParams {
+	c.t.Helper()
+
+	var msg map[string]json.RawMessage
+	if len(c.pending) > 0 {
+		msg, c.pending = c.pending[0], c.pending[1:]
+	} else {
+		var ok bool
+		if msg, ok = <-c.msgs; !ok {
+			c.t.Fatalf("connection closed waiting for diagnostics")
+		}
+	}
+
+	var method string
+	json.Unmarshal(msg["method"], &method)
+	if method != "textDocument/publishDiagnostics" {
+		c.t.Fatalf("expected publishDiagnostics, got %s", method)
+	}
+
+	var params publishDiagnosticsParams
+	if err := json.Unmarshal(msg["params"], &params); err != nil {
+		c.t.Fatalf("decoding diagnostics failed: %v", err)
+	}
+	return params
+}
+
+func (c *client) open(uri, text string) publishDiagnosticsParams {
+	c.t.Helper()
+
+	c.notify("textDocument/didOpen", didOpenParams{
+		TextDocument: textDocumentItem{URI: uri, LanguageID: "gorilla", Version: 1, Text: text},
+	})
+	return c.diagnostics()
+}
+
+// close shuts the server down and waits for it to finish.
+func (c *client) close() {
+	c.t.Helper()
+
+	c.request("shutdown", nil, nil)
+	c.notify("exit", nil)
+
+	if err := <-c.done; err != nil {
+		c.t.Errorf("server finished with error: %v", err)
+	}
+}
+
+const uri = "file:///test.gr"
+
+func TestDiagnostics(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+
+	got := c.open(uri, "let x = 5;\nif x { x }")
+
+	if got.URI != uri {
+		t.Errorf("diagnostics for wrong document, got=%s", got.URI)
+	}
+
+	if len(got.Diagnostics) == 0 {
+		t.Fatalf("expected diagnostics")
+	}
+
+	want := diagnostic{
+		Range:    rangeLSP{Start: position{1, 3}, End: position{1, 4}},
+		Severity: severityError,
+		Source:   "gorilla",
+		Message:  "expected next token to be LPAREN, got IDENTIFIER instead",
+	}
+	if !reflect.DeepEqual(got.Diagnostics[0], want) {
+		t.Errorf("wrong diagnostic,\nwant=%+v\ngot= %+v", want, got.Diagnostics[0])
+	}
+
+	c.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": uri, "version": 2},
+		"contentChanges": []map[string]string{{"text": "let x = 5;\nif (x) { x }"}},
+	})
+
+	if got := c.diagnostics(); len(got.Diagnostics) != 0 {
+		t.Errorf("expected no diagnostics after fixing the document, got=%+v", got.Diagnostics)
+	}
+
+	c.notify("textDocument/didClose", didCloseParams{TextDocument: textDocumentIdentifier{URI: uri}})
+
+	if got := c.diagnostics(); len(got.Diagnostics) != 0 {
+		t.Errorf("expected diagnostics to be cleared on close, got=%+v", got.Diagnostics)
+	}
+}
+
+func TestDocumentSymbol(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+
+	c.open(uri, "let x = 5;\nlet y = 1;\nif (x < y) { x }\nf := fn() { };")
+
+	var symbols []documentSymbol
+	c.request("textDocument/documentSymbol", documentSymbolParams{TextDocument: textDocumentIdentifier{URI: uri}}, &symbols)
+
+	want := []documentSymbol{
+		{
+			Name:           "x",
+			Kind:           symbolKindVariable,
+			Range:          rangeLSP{Start: position{0, 0}, End: position{0, 5}},
+			SelectionRange: rangeLSP{Start: position{0, 4}, End: position{0, 5}},
+		},
+		{
+			Name:           "y",
+			Kind:           symbolKindVariable,
+			Range:          rangeLSP{Start: position{1, 0}, End: position{1, 5}},
+			SelectionRange: rangeLSP{Start: position{1, 4}, End: position{1, 5}},
+		},
+		{
+			Name:           "f",
+			Kind:           symbolKindFunction,
+			Range:          rangeLSP{Start: position{3, 0}, End: position{3, 1}},
+			SelectionRange: rangeLSP{Start: position{3, 0}, End: position{3, 1}},
+		},
+	}
+
+	if !reflect.DeepEqual(symbols, want) {
+		t.Errorf("wrong symbols,\nwant=%+v\ngot= %+v", want, symbols)
+	}
+}
+
+func TestHover(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+
+	c.open(uri, "a + -b;")
+
+	tests := []struct {
+		pos  position
+		kind string
+	}{
+		{position{0, 0}, "Identifier"},
+		{position{0, 2}, "InfixExpression"},
+		{position{0, 4}, "PrefixExpression"},
+		{position{0, 5}, "Identifier"},
+	}
+
+	for _, tt := range tests {
+		var got hover
+		c.request("textDocument/hover", textDocumentPositionParams{
+			TextDocument: textDocumentIdentifier{URI: uri},
+			Position:     tt.pos,
+		}, &got)
+
+		if got.Contents.Value != tt.kind {
+			t.Errorf("hover at %+v wrong, want=%s got=%s", tt.pos, tt.kind, got.Contents.Value)
+		}
+		if got.Range.Start != tt.pos {
+			t.Errorf("hover range at %+v starts at %+v", tt.pos, got.Range.Start)
+		}
+	}
+
+	var got *hover
+	c.request("textDocument/hover", textDocumentPositionParams{
+		TextDocument: textDocumentIdentifier{URI: uri},
+		Position:     position{3, 0},
+	}, &got)
+
+	if got != nil {
+		t.Errorf("expected no hover outside the program, got=%+v", got)
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+
+	c.open(uri, `let x = 1;
+fn(x, y) { x + y };
+x;
+z;
+for (x in x) { x };
+fn() { x := x; };
+match y { [x, ...y] if x => y, _ => x };`)
+
+	tests := []struct {
+		pos  position
+		want *rangeLSP
+	}{
+		// The parameter x shadows the top level binding in the body.
+		{position{1, 11}, &rangeLSP{Start: position{1, 3}, End: position{1, 4}}},
+		{position{1, 15}, &rangeLSP{Start: position{1, 6}, End: position{1, 7}}},
+		{position{2, 0}, &rangeLSP{Start: position{0, 4}, End: position{0, 5}}},
+		// Declarations are their own definition.
+		{position{0, 4}, &rangeLSP{Start: position{0, 4}, End: position{0, 5}}},
+		// Unbound identifiers have no definition.
+		{position{3, 0}, nil},
+		// The loop variable is bound in the body, not in the iterable.
+		{position{4, 10}, &rangeLSP{Start: position{0, 4}, End: position{0, 5}}},
+		{position{4, 15}, &rangeLSP{Start: position{4, 5}, End: position{4, 6}}},
+		// A short declaration is not visible in its own value.
+		{position{5, 12}, &rangeLSP{Start: position{0, 4}, End: position{0, 5}}},
+		// Match patterns bind in their own arm only.
+		{position{6, 6}, nil},
+		{position{6, 23}, &rangeLSP{Start: position{6, 11}, End: position{6, 12}}},
+		{position{6, 28}, &rangeLSP{Start: position{6, 17}, End: position{6, 18}}},
+		{position{6, 36}, &rangeLSP{Start: position{0, 4}, End: position{0, 5}}},
+	}
+
+	for _, tt := range tests {
+		var got *location
+		c.request("textDocument/definition", textDocumentPositionParams{
+			TextDocument: textDocumentIdentifier{URI: uri},
+			Position:     tt.pos,
+		}, &got)
+
+		if tt.want == nil {
+			if got != nil {
+				t.Errorf("expected no definition at %+v, got=%+v", tt.pos, got)
+			}
+			continue
+		}
+
+		if got == nil {
+			t.Errorf("expected a definition at %+v", tt.pos)
+			continue
+		}
+		if got.URI != uri || got.Range != *tt.want {
+			t.Errorf("definition at %+v wrong, want=%+v got=%+v", tt.pos, *tt.want, got.Range)
+		}
+	}
+}
+
+func TestUTF16Positions(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+
+	// The emoji is four bytes long but two UTF-16 code units.
+	c.open(uri, "let a = 1;\n\U0001F600 a;")
+
+	var got hover
+	c.request("textDocument/hover", textDocumentPositionParams{
+		TextDocument: textDocumentIdentifier{URI: uri},
+		Position:     position{1, 3},
+	}, &got)
+
+	want := rangeLSP{Start: position{1, 3}, End: position{1, 4}}
+	if got.Contents.Value != "Identifier" || got.Range != want {
+		t.Errorf("wrong hover, want=Identifier %+v got=%s %+v", want, got.Contents.Value, got.Range)
+	}
+}
+
+func TestMultilineToken(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+
+	c.open(uri, "x; \"a\nb\";")
+
+	want := rangeLSP{Start: position{0, 3}, End: position{1, 2}}
+	for _, pos := range []position{{0, 3}, {1, 1}} {
+		var got hover
+		c.request("textDocument/hover", textDocumentPositionParams{
+			TextDocument: textDocumentIdentifier{URI: uri},
+			Position:     pos,
+		}, &got)
+
+		if got.Contents.Value != "StringLiteral" || got.Range != want {
+			t.Errorf("wrong hover at %+v, want=StringLiteral %+v got=%s %+v", pos, want, got.Contents.Value, got.Range)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+
+	rerr := c.call("workspace/symbol", map[string]interface{}{}, nil)
+	if rerr == nil || rerr.Code != codeMethodNotFound {
+		t.Errorf("expected method not found, got=%v", rerr)
+	}
+
+	rerr = c.call("textDocument/hover", textDocumentPositionParams{
+		TextDocument: textDocumentIdentifier{URI: "file:///missing.gr"},
+	}, nil)
+	if rerr == nil || rerr.Code != codeInvalidParams {
+		t.Errorf("expected invalid params for an unknown document, got=%v", rerr)
+	}
+}
@@ -0,0 +1,144 @@
+// Package module loads gorilla programs spread over several files.
+//
+// A file is a module. It names the modules it depends on with import
+// statements, whose paths are resolved relative to the importing file,
+// and makes bindings available to its importers with export statements.
+// The Loader parses every module once, in dependency order, and rejects
+// import cycles.
+package module
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/juanfgarcia/gorilla/ast"
+	"github.com/juanfgarcia/gorilla/parser"
+)
+
+// Extension is appended to import paths that have none.
+const Extension = ".gr"
+
+type Module struct {
+	// Path is the cleaned path the module was read from.
+	Path    string
+	Program *ast.Program
+
+	// Imports are the modules imported by this one, in source order.
+	Imports []*Module
+
+	// Exports are the names bound by export statements, in source order.
+	Exports []string
+}
+
+// ParseError reports the parse errors of a module.
+type ParseError struct {
+	Path   string
+	Errors []parser.Error
+}
+
+func (e *ParseError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = e.Path + ":" + err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// CycleError reports an import cycle; Cycle starts and ends with the
+// same path.
+type CycleError struct {
+	Cycle []string
+}
+
+func (e *CycleError) Error() string {
+	return "import cycle: " + strings.Join(e.Cycle, " -> ")
+}
+
+type Loader struct {
+	// ReadFile reads the source of a module, os.ReadFile by default.
+	ReadFile func(path string) ([]byte, error)
+
+	modules map[string]*Module
+
+	// loading is the chain of modules being loaded, used to find cycles.
+	loading []string
+}
+
+func NewLoader() *Loader {
+	return &Loader{
+		ReadFile: os.ReadFile,
+		modules:  map[string]*Module{},
+	}
+}
+
+// Load returns the module at path with all its imports loaded. Modules
+// already loaded by this Loader are shared rather than read again.
+func (l *Loader) Load(path string) (*Module, error) {
+	return l.load(resolve("", path))
+}
+
+func (l *Loader) load(path string) (*Module, error) {
+	for i, loading := range l.loading {
+		if loading == path {
+			cycle := append([]string{}, l.loading[i:]...)
+			return nil, &CycleError{Cycle: append(cycle, path)}
+		}
+	}
+
+	if mod, ok := l.modules[path]; ok {
+		return mod, nil
+	}
+
+	src, err := l.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser.New(string(src))
+	program := p.ParseProgram()
+	if errors := p.Diagnostics(); len(errors) != 0 {
+		return nil, &ParseError{Path: path, Errors: errors}
+	}
+
+	mod := &Module{Path: path, Program: program, Imports: []*Module{}, Exports: []string{}}
+
+	l.loading = append(l.loading, path)
+	defer func() { l.loading = l.loading[:len(l.loading)-1] }()
+
+	exported := map[string]bool{}
+
+	for _, stmt := range program.Statements {
+		switch stmt := stmt.(type) {
+		case *ast.ImportStatement:
+			imported, err := l.load(resolve(path, stmt.Path.Value))
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d:%d: %w", path, stmt.Token.Line, stmt.Token.Column, err)
+			}
+			mod.Imports = append(mod.Imports, imported)
+		case *ast.ExportStatement:
+			name := stmt.Statement.Name.Value
+			if exported[name] {
+				return nil, fmt.Errorf("%s:%d:%d: %s exported twice", path, stmt.Token.Line, stmt.Token.Column, name)
+			}
+			exported[name] = true
+			mod.Exports = append(mod.Exports, name)
+		}
+	}
+
+	l.modules[path] = mod
+	return mod, nil
+}
+
+// resolve returns the path of the module imported as path from the
+// module at importer, relative to the importer's directory.
+func resolve(importer, path string) string {
+	if filepath.Ext(path) == "" {
+		path += Extension
+	}
+	if importer != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(importer), path)
+	}
+	return filepath.Clean(path)
+}
@@ -0,0 +1,113 @@
+package module
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// files returns a Loader reading from an in-memory file system.
+func files(fs map[string]string) *Loader {
+	l := NewLoader()
+	l.ReadFile = func(path string) ([]byte, error) {
+		src, ok := fs[path]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return []byte(src), nil
+	}
+	return l
+}
+
+func TestLoad(t *testing.T) {
+	l := files(map[string]string{
+		"main.gr":         `import "lib/math"; import "lib/strings.gr"; let x = 1;`,
+		"lib/math.gr":     `import "strings"; export let double = fn(x) { x * 2 }; let hidden = 1; export let pi = 3;`,
+		"lib/strings.gr":  `export let empty = "";`,
+		"lib/unused.gr":   `let never = 1;`,
+		"other/double.gr": `export let double = 0;`,
+	})
+
+	main, err := l.Load("main")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if main.Path != "main.gr" {
+		t.Errorf("main.Path wrong, got=%q", main.Path)
+	}
+
+	if len(main.Imports) != 2 {
+		t.Fatalf("main should import 2 modules, got=%d", len(main.Imports))
+	}
+
+	math, str := main.Imports[0], main.Imports[1]
+
+	if math.Path != "lib/math.gr" || str.Path != "lib/strings.gr" {
+		t.Errorf("imports resolved to wrong paths, got=%q and %q", math.Path, str.Path)
+	}
+
+	if !reflect.DeepEqual(math.Exports, []string{"double", "pi"}) {
+		t.Errorf("math.Exports wrong, got=%v", math.Exports)
+	}
+
+	if len(math.Imports) != 1 || math.Imports[0] != str {
+		t.Errorf("lib/strings should be loaded once and shared, got=%v", math.Imports)
+	}
+
+	if len(main.Exports) != 0 {
+		t.Errorf("main exports nothing, got=%v", main.Exports)
+	}
+}
+
+func TestLoadCycle(t *testing.T) {
+	l := files(map[string]string{
+		"a.gr":     `import "dir/b";`,
+		"dir/b.gr": `import "c";`,
+		"dir/c.gr": `import "../a";`,
+	})
+
+	_, err := l.Load("a.gr")
+
+	var cycle *CycleError
+	if !errors.As(err, &cycle) {
+		t.Fatalf("expected a *CycleError, got=%v", err)
+	}
+
+	want := []string{"a.gr", "dir/b.gr", "dir/c.gr", "a.gr"}
+	if !reflect.DeepEqual(cycle.Cycle, want) {
+		t.Errorf("cycle wrong, want=%v got=%v", want, cycle.Cycle)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	l := files(map[string]string{
+		"main.gr":    `import "broken";`,
+		"broken.gr":  "let x = 1;\nif x { x }",
+		"missing.gr": `import "nowhere";`,
+		"twice.gr":   `export let a = 1; export let a = 2;`,
+	})
+
+	_, err := l.Load("main.gr")
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected a *ParseError, got=%v", err)
+	}
+	if parseErr.Path != "broken.gr" || len(parseErr.Errors) == 0 {
+		t.Errorf("wrong parse error, got=%+v", parseErr)
+	}
+	if !strings.HasPrefix(err.Error(), "main.gr:1:1: broken.gr:2:4: expected next token to be LPAREN") {
+		t.Errorf("wrong message, got=%q", err.Error())
+	}
+
+	if _, err := l.Load("missing.gr"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a missing file error, got=%v", err)
+	}
+
+	if _, err := l.Load("twice.gr"); err == nil || err.Error() != "twice.gr:1:19: a exported twice" {
+		t.Errorf("expected duplicate export error, got=%v", err)
+	}
+}
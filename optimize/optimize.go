@@ -0,0 +1,431 @@
+// Package optimize simplifies syntax trees before they are run.
+//
+// Fold evaluates prefix and infix expressions whose operands are integer,
+// float or boolean literals and simplifies if expressions whose condition
+// is a literal to the branch that would be taken. Operations that would
+// fail at run time, such as a division by zero, are left in place and
+// reported as diagnostics, unless they are in code that would never run.
+//
+// Arithmetic between two integers stays integer arithmetic, with
+// division truncating toward zero. It is exact: a result that does not
+// fit in an int64 becomes a big integer literal, and one that fits again
+// goes back to a plain integer literal. When either operand is a float the
+// integer is converted to float first, so 7 / 2 is 3 but 7 / 2.0 and
+// 7.0 / 2 are 3.5, and comparisons such as 1 == 1.0 compare the
+// converted values.
+//
+// The bitwise operators & | ^ ~ << and >> apply to integers only, which
+// behave as if in two's complement with infinitely many bits, so ~5 is
+// -6 and -8 >> 1 is -4. Shifting by a negative count is reported as a
+// diagnostic.
+//
+// The logical operators && and || evaluate to a boolean and short
+// circuit: when the left operand is a literal that decides the result,
+// as in false && x or 1 || x, the right operand is dropped along with
+// any diagnostic about it, just as it would not be evaluated at run time.
+package optimize
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/juanfgarcia/gorilla/ast"
+	"github.com/juanfgarcia/gorilla/token"
+)
+
+// Diagnostic is a problem found while folding, located at the token of
+// the offending expression.
+type Diagnostic struct {
+	Token token.Token
+	Msg   string
+}
+
+func (d Diagnostic) Error() string {
+	return fmt.Sprintf("%d:%d: %s", d.Token.Line, d.Token.Column, d.Msg)
+}
+
+// Fold rewrites the tree rooted at node in place, bottom up, and returns
+// the new root along with the diagnostics found.
+func Fold(node ast.Node) (ast.Node, []Diagnostic) {
+	f := &folder{diagnostics: []Diagnostic{}}
+	return ast.Modify(node, f.fold), f.diagnostics
+}
+
+// maxShift is the largest left shift that is folded.
+const maxShift = 1 << 16
+
+type folder struct {
+	diagnostics []Diagnostic
+
+	// reported holds the expression each diagnostic is about.
+	reported []ast.Node
+}
+
+func (f *folder) errorf(node *ast.InfixExpression, format string, args ...interface{}) {
+	f.diagnostics = append(f.diagnostics, Diagnostic{Token: node.Token, Msg: fmt.Sprintf(format, args...)})
+	f.reported = append(f.reported, node)
+}
+
+// discard drops the diagnostics about expressions under node, which the
+// fold removed or found would never run. Children are folded before
+// their parents, so those were reported before it was known.
+func (f *folder) discard(node ast.Node) {
+	if len(f.diagnostics) == 0 {
+		return
+	}
+
+	dead := map[ast.Node]bool{}
+	var visit func(node ast.Node)
+	visit = func(node ast.Node) {
+		dead[node] = true
+		for _, child := range ast.Children(node) {
+			visit(child.Node)
+		}
+	}
+	visit(node)
+
+	diagnostics, reported := []Diagnostic{}, []ast.Node{}
+	for i, n := range f.reported {
+		if !dead[n] {
+			diagnostics = append(diagnostics, f.diagnostics[i])
+			reported = append(reported, n)
+		}
+	}
+	f.diagnostics, f.reported = diagnostics, reported
+}
+
+func (f *folder) fold(node ast.Node) ast.Node {
+	switch node := node.(type) {
+	case *ast.ExpressionStatement:
+		// A statement has no value to keep, so the branch itself can
+		// take its place.
+		if ie, ok := node.Expression.(*ast.IfExpression); ok {
+			if taken, _, ok := branches(ie); ok && taken != nil {
+				return taken
+			}
+		}
+	case *ast.PrefixExpression:
+		if folded := f.foldPrefix(node); folded != nil {
+			return folded
+		}
+	case *ast.InfixExpression:
+		if folded := f.foldInfix(node); folded != nil {
+			return folded
+		}
+	case *ast.IfExpression:
+		if folded := f.foldIf(node); folded != nil {
+			return folded
+		}
+	}
+	return node
+}
+
+func (f *folder) foldPrefix(node *ast.PrefixExpression) ast.Expression {
+	switch right := node.Right.(type) {
+	case *ast.IntegerLiteral, *ast.BigIntegerLiteral:
+		value, _, _ := exact(right)
+		switch node.Operator {
+		case "-":
+			return integer(node.Token, new(big.Int).Neg(value))
+		case "~":
+			return integer(node.Token, new(big.Int).Not(value))
+		case "!":
+			// Every integer is truthy.
+			return boolean(node.Token, false)
+		}
+	case *ast.FloatLiteral:
+		switch node.Operator {
+		case "-":
+			return float(node.Token, -right.Value)
+		case "!":
+			// Every float is truthy.
+			return boolean(node.Token, false)
+		}
+	case *ast.Boolean:
+		if node.Operator == "!" {
+			return boolean(node.Token, !right.Value)
+		}
+	}
+	return nil
+}
+
+func (f *folder) foldInfix(node *ast.InfixExpression) ast.Expression {
+	if node.Operator == "&&" || node.Operator == "||" {
+		return f.foldLogical(node)
+	}
+
+	if left, tok, ok := exact(node.Left); ok {
+		if right, _, ok := exact(node.Right); ok {
+			return f.foldIntegerInfix(node, tok, left, right)
+		}
+	}
+
+	if left, tok, ok := promote(node.Left); ok {
+		if right, _, ok := promote(node.Right); ok {
+			return f.foldFloatInfix(node, tok, left, right)
+		}
+		return nil
+	}
+
+	if left, ok := node.Left.(*ast.Boolean); ok {
+		right, ok := node.Right.(*ast.Boolean)
+		if !ok {
+			return nil
+		}
+		switch node.Operator {
+		case "==":
+			return boolean(left.Token, left.Value == right.Value)
+		case "!=":
+			return boolean(left.Token, left.Value != right.Value)
+		}
+	}
+	return nil
+}
+
+// foldLogical folds && and || when the left operand is a literal and
+// either decides the result or is followed by another literal.
+func (f *folder) foldLogical(node *ast.InfixExpression) ast.Expression {
+	left, tok, ok := truthy(node.Left)
+	if !ok {
+		return nil
+	}
+
+	// true || x and false && x do not depend on x.
+	if left == (node.Operator == "||") {
+		f.discard(node.Right)
+		return boolean(tok, left)
+	}
+
+	right, _, ok := truthy(node.Right)
+	if !ok {
+		return nil
+	}
+	return boolean(tok, right)
+}
+
+// truthy reports whether a literal counts as true in a condition, along
+// with the literal's token. Only false is false among the literals.
+func truthy(exp ast.Expression) (bool, token.Token, bool) {
+	switch exp := exp.(type) {
+	case *ast.Boolean:
+		return exp.Value, exp.Token, true
+	case *ast.IntegerLiteral:
+		return true, exp.Token, true
+	case *ast.BigIntegerLiteral:
+		return true, exp.Token, true
+	case *ast.FloatLiteral:
+		return true, exp.Token, true
+	}
+	return false, token.Token{}, false
+}
+
+// exact returns the value of an integer literal of either size, along
+// with the literal's token.
+func exact(exp ast.Expression) (*big.Int, token.Token, bool) {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral:
+		return big.NewInt(exp.Value), exp.Token, true
+	case *ast.BigIntegerLiteral:
+		return exp.Value, exp.Token, true
+	}
+	return nil, token.Token{}, false
+}
+
+// promote returns the value of an integer or float literal as a float,
+// along with the literal's token.
+func promote(exp ast.Expression) (float64, token.Token, bool) {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral:
+		return float64(exp.Value), exp.Token, true
+	case *ast.BigIntegerLiteral:
+		value, _ := new(big.Float).SetInt(exp.Value).Float64()
+		return value, exp.Token, true
+	case *ast.FloatLiteral:
+		return exp.Value, exp.Token, true
+	}
+	return 0, token.Token{}, false
+}
+
+func (f *folder) foldIntegerInfix(node *ast.InfixExpression, tok token.Token, left, right *big.Int) ast.Expression {
+	value := new(big.Int)
+
+	switch node.Operator {
+	case "+":
+		return integer(tok, value.Add(left, right))
+	case "-":
+		return integer(tok, value.Sub(left, right))
+	case "*":
+		return integer(tok, value.Mul(left, right))
+	case "/":
+		if right.Sign() == 0 {
+			f.errorf(node, "division by zero in %s", node.String())
+			return nil
+		}
+		return integer(tok, value.Quo(left, right))
+	case "%":
+		if right.Sign() == 0 {
+			f.errorf(node, "modulo by zero in %s", node.String())
+			return nil
+		}
+		return integer(tok, value.Rem(left, right))
+	case "&":
+		return integer(tok, value.And(left, right))
+	case "|":
+		return integer(tok, value.Or(left, right))
+	case "^":
+		return integer(tok, value.Xor(left, right))
+	case "<<", ">>":
+		if right.Sign() < 0 {
+			f.errorf(node, "negative shift count in %s", node.String())
+			return nil
+		}
+		if node.Operator == ">>" {
+			// Shifting right by more bits than left has gives 0 or -1,
+			// the same as shifting by exactly that many.
+			n := uint(left.BitLen())
+			if right.IsUint64() && right.Uint64() < uint64(n) {
+				n = uint(right.Uint64())
+			}
+			return integer(tok, value.Rsh(left, n))
+		}
+		// Larger shifts are left for run time rather than grow the
+		// literal without bound.
+		if !right.IsUint64() || right.Uint64() > maxShift {
+			return nil
+		}
+		return integer(tok, value.Lsh(left, uint(right.Uint64())))
+	case "<":
+		return boolean(tok, left.Cmp(right) < 0)
+	case ">":
+		return boolean(tok, left.Cmp(right) > 0)
+	case "<=":
+		return boolean(tok, left.Cmp(right) <= 0)
+	case ">=":
+		return boolean(tok, left.Cmp(right) >= 0)
+	case "==":
+		return boolean(tok, left.Cmp(right) == 0)
+	case "!=":
+		return boolean(tok, left.Cmp(right) != 0)
+	}
+	return nil
+}
+
+func (f *folder) foldFloatInfix(node *ast.InfixExpression, tok token.Token, left, right float64) ast.Expression {
+	var value float64
+
+	switch node.Operator {
+	case "+":
+		value = left + right
+	case "-":
+		value = left - right
+	case "*":
+		value = left * right
+	case "/":
+		if right == 0 {
+			f.errorf(node, "division by zero in %s", node.String())
+			return nil
+		}
+		value = left / right
+	case "%":
+		if right == 0 {
+			f.errorf(node, "modulo by zero in %s", node.String())
+			return nil
+		}
+		value = math.Mod(left, right)
+	case "<":
+		return boolean(tok, left < right)
+	case ">":
+		return boolean(tok, left > right)
+	case "<=":
+		return boolean(tok, left <= right)
+	case ">=":
+		return boolean(tok, left >= right)
+	case "==":
+		return boolean(tok, left == right)
+	case "!=":
+		return boolean(tok, left != right)
+	default:
+		return nil
+	}
+
+	// Infinities have no literal to be folded into.
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return nil
+	}
+	return float(tok, value)
+}
+
+// foldIf replaces an if expression whose condition is a literal with the
+// value of the block that would run, when that block is a single
+// expression. Otherwise the if is kept, as it may be evaluating to null
+// or to the value of a block with several statements.
+func (f *folder) foldIf(node *ast.IfExpression) ast.Expression {
+	taken, skipped, ok := branches(node)
+	if !ok {
+		return nil
+	}
+
+	if skipped != nil {
+		f.discard(skipped)
+	}
+
+	if taken == nil || len(taken.Statements) != 1 {
+		return nil
+	}
+	if stmt, ok := taken.Statements[0].(*ast.ExpressionStatement); ok && stmt.Expression != nil {
+		return stmt.Expression
+	}
+	return nil
+}
+
+// branches returns the block an if expression whose condition is a
+// literal would run and the one it would skip, either of which may be
+// nil.
+func branches(node *ast.IfExpression) (taken, skipped *ast.BlockStatement, ok bool) {
+	cond, _, ok := truthy(node.Condition)
+	if !ok {
+		return nil, nil, false
+	}
+
+	if cond {
+		return node.Consequence, node.Alternative, true
+	}
+	return node.Alternative, node.Consequence, true
+}
+
+// integer returns an integer literal, big only when value does not fit
+// in an int64.
+func integer(at token.Token, value *big.Int) ast.Expression {
+	tok := token.Token{Typ: token.INT, Literal: value.String(), Line: at.Line, Column: at.Column}
+	if !value.IsInt64() {
+		return &ast.BigIntegerLiteral{Token: tok, Value: value}
+	}
+	return &ast.IntegerLiteral{Token: tok, Value: value.Int64()}
+}
+
+// float returns a float literal whose token always lexes as a float,
+// so that 2.0 is not printed as the integer 2.
+func float(at token.Token, value float64) *ast.FloatLiteral {
+	literal := strconv.FormatFloat(value, 'g', -1, 64)
+	if !strings.ContainsAny(literal, ".e") {
+		literal += ".0"
+	}
+	return &ast.FloatLiteral{
+		Token: token.Token{Typ: token.FLOAT, Literal: literal, Line: at.Line, Column: at.Column},
+		Value: value,
+	}
+}
+
+func boolean(at token.Token, value bool) *ast.Boolean {
+	typ := token.TokenType(token.FALSE)
+	if value {
+		typ = token.TRUE
+	}
+	return &ast.Boolean{
+		Token: token.Token{Typ: typ, Literal: strconv.FormatBool(value), Line: at.Line, Column: at.Column},
+		Value: value,
+	}
+}
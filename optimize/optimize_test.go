@@ -0,0 +1,275 @@
+package optimize
+
+import (
+	"github.com/juanfgarcia/gorilla/ast"
+	"github.com/juanfgarcia/gorilla/parser"
+	"testing"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+
+	p := parser.New(input)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"2 * 60 * 60;", "7200"},
+		{"1 + 2 * 3 - 4 / 2;", "5"},
+		{"-(3 - 5);", "2"},
+		{"7 / 2;", "3"},
+		{"!true;", "false"},
+		{"!!false;", "false"},
+		{"!5;", "false"},
+		{"1 < 2;", "true"},
+		{"3 > 5 == false;", "true"},
+		{"true != false;", "true"},
+		{"1 + 1 == 2;", "true"},
+		{"x * (2 + 3);", "(x * 5)"},
+		{"x + 2 + 3;", "((x + 2) + 3)"},
+		{"true == 1;", "(true == 1)"},
+		{"if (1 < 2) { x } else { y }", "x"},
+		{"if (!true) { x } else { y }", "y"},
+		{"if (false) { x }", "if false x"},
+		{"x + if (false) { 1 };", "(x + if false 1)"},
+		{"1 + if (true) { 1 } else { 2 };", "2"},
+		{"x + if (true) { 1; 2 };", "(x + if true 12)"},
+		{"if (true) { 1; 2 }", "12"},
+		{"if (5) { x }", "x"},
+		{"7 / 2.0;", "3.5"},
+		{"7.0 / 2;", "3.5"},
+		{"0.5 + 0.5;", "1.0"},
+		{"1.5 * 2;", "3.0"},
+		{"1e20 * 1e20;", "1e+40"},
+		{"-2.5;", "-2.5"},
+		{"!0.5;", "false"},
+		{"1 == 1.0;", "true"},
+		{"2.5 > 2;", "true"},
+		{"0.1 + 0.2 != 0.3;", "true"},
+		{"1e308 * 10.0;", "(1e308 * 10.0)"},
+		{"if (0.0) { x }", "x"},
+		{"9223372036854775807 + 1;", "9223372036854775808"},
+		{"4294967296 * 4294967296;", "18446744073709551616"},
+		{"-9223372036854775807 - 2;", "-9223372036854775809"},
+		{"9223372036854775808 - 1;", "9223372036854775807"},
+		{"-9223372036854775808;", "-9223372036854775808"},
+		{"100000000000000000000 / 3;", "33333333333333333333"},
+		{"-7 / 2;", "-3"},
+		{"18446744073709551616 > 9223372036854775807;", "true"},
+		{"18446744073709551616 / 2.0;", "9.223372036854776e+18"},
+		{"if (18446744073709551616) { x }", "x"},
+		{"7 % 3;", "1"},
+		{"-7 % 3;", "-1"},
+		{"18446744073709551617 % 2;", "1"},
+		{"7.5 % 2;", "1.5"},
+		{"2 <= 2;", "true"},
+		{"3 >= 4;", "false"},
+		{"2.5 <= 2;", "false"},
+		{"false && x;", "false"},
+		{"true || x;", "true"},
+		{"1 || x;", "true"},
+		{"true && x;", "(true && x)"},
+		{"false || x;", "(false || x)"},
+		{"x && false;", "(x && false)"},
+		{"true && 5;", "true"},
+		{"false || false;", "false"},
+		{"1 < 2 && 2 < 3;", "true"},
+		{"12 & 10;", "8"},
+		{"12 | 3;", "15"},
+		{"12 ^ 10;", "6"},
+		{"~5;", "-6"},
+		{"~-1;", "0"},
+		{"-6 & 7;", "2"},
+		{"1 << 3;", "8"},
+		{"1 << 64;", "18446744073709551616"},
+		{"-8 >> 1;", "-4"},
+		{"-8 >> 100;", "-1"},
+		{"8 >> 100;", "0"},
+		{"0xF0 | 0x0F == 0xFF;", "true"},
+		{"1 << 100000;", "(1 << 100000)"},
+		{"1.5 & 1;", "(1.5 & 1)"},
+		{"~1.5;", "(~1.5)"},
+		{"while (1 < 2) { x + 2 * 3 }", "while true (x + 6)"},
+		{"for (x in 1 + 1) { if (true) { x } }", "for x in 2 x"},
+		{"if (x) { 1 + 1 } else { 2 * 2 }", "if x 2else 4"},
+		{"fn(x) { x * (4 / 2) }", "fn( x,  )(x * 2)"},
+	}
+
+	for _, tt := range tests {
+		program := parse(t, tt.input)
+
+		folded, diagnostics := Fold(program)
+		if len(diagnostics) != 0 {
+			t.Errorf("unexpected diagnostics for %q: %v", tt.input, diagnostics)
+		}
+
+		if got := folded.String(); got != tt.expected {
+			t.Errorf("Fold(%q) wrong, want=%q got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestFoldLiteralTokens(t *testing.T) {
+	program := parse(t, "x;\n  2 * 3;")
+
+	folded, _ := Fold(program)
+
+	stmt := folded.(*ast.Program).Statements[1].(*ast.ExpressionStatement)
+	lit, ok := stmt.Expression.(*ast.IntegerLiteral)
+	if !ok {
+		t.Fatalf("expression not folded into *ast.IntegerLiteral. got=%T", stmt.Expression)
+	}
+
+	if lit.Value != 6 || lit.TokenLiteral() != "6" {
+		t.Errorf("wrong literal, got value=%d literal=%q", lit.Value, lit.TokenLiteral())
+	}
+	if lit.Token.Line != 2 || lit.Token.Column != 3 {
+		t.Errorf("folded literal should keep the position of the expression, got=%d:%d", lit.Token.Line, lit.Token.Column)
+	}
+}
+
+func TestFoldOverflow(t *testing.T) {
+	program := parse(t, "9223372036854775807 * 2;")
+
+	folded, _ := Fold(program)
+
+	stmt := folded.(*ast.Program).Statements[0].(*ast.ExpressionStatement)
+	lit, ok := stmt.Expression.(*ast.BigIntegerLiteral)
+	if !ok {
+		t.Fatalf("overflow not folded into *ast.BigIntegerLiteral. got=%T", stmt.Expression)
+	}
+
+	if lit.Value.String() != "18446744073709551614" {
+		t.Errorf("wrong value, got=%s", lit.Value)
+	}
+
+	// A big result that fits again is a plain integer.
+	folded, _ = Fold(parse(t, "9223372036854775807 * 2 - 9223372036854775807;"))
+
+	stmt = folded.(*ast.Program).Statements[0].(*ast.ExpressionStatement)
+	if _, ok := stmt.Expression.(*ast.IntegerLiteral); !ok {
+		t.Errorf("result should be an *ast.IntegerLiteral. got=%T", stmt.Expression)
+	}
+}
+
+func TestFoldDivisionByZero(t *testing.T) {
+	program := parse(t, "1 + 10 / (5 - 5);")
+
+	folded, diagnostics := Fold(program)
+
+	if got := folded.String(); got != "(1 + (10 / 0))" {
+		t.Errorf("division by zero should not be folded, got=%q", got)
+	}
+
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic, got=%v", diagnostics)
+	}
+
+	want := "1:8: division by zero in (10 / 0)"
+	if diagnostics[0].Error() != want {
+		t.Errorf("wrong diagnostic, want=%q got=%q", want, diagnostics[0].Error())
+	}
+}
+
+func TestFoldDeadCode(t *testing.T) {
+	tests := []struct {
+		input       string
+		diagnostics int
+	}{
+		{"if (false) { 1 / 0 }", 0},
+		{"if (true) { 1 } else { 1 / 0 }", 0},
+		{"x + if (false) { 1 / 0; 2 };", 0},
+		{"if (true) { 1 / 0 }", 1},
+		{"if (x) { 1 / 0 }", 1},
+	}
+
+	for _, tt := range tests {
+		_, diagnostics := Fold(parse(t, tt.input))
+
+		if len(diagnostics) != tt.diagnostics {
+			t.Errorf("Fold(%q) wrong diagnostics, want %d got=%v", tt.input, tt.diagnostics, diagnostics)
+		}
+	}
+}
+
+func TestFoldShortCircuit(t *testing.T) {
+	program := parse(t, "false && 1 / 0; true || 1 / 0;")
+
+	folded, diagnostics := Fold(program)
+
+	if got := folded.String(); got != "falsetrue" {
+		t.Errorf("right operands should be dropped, got=%q", got)
+	}
+
+	// The right operands would never run.
+	if len(diagnostics) != 0 {
+		t.Errorf("expected no diagnostics, got=%v", diagnostics)
+	}
+
+	_, diagnostics = Fold(parse(t, "true && 1 / 0;"))
+	if len(diagnostics) != 1 {
+		t.Errorf("expected 1 diagnostic, got=%v", diagnostics)
+	}
+}
+
+func TestFoldModuloByZero(t *testing.T) {
+	program := parse(t, "10 % (2 - 2);")
+
+	_, diagnostics := Fold(program)
+
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic, got=%v", diagnostics)
+	}
+
+	want := "1:4: modulo by zero in (10 % 0)"
+	if diagnostics[0].Error() != want {
+		t.Errorf("wrong diagnostic, want=%q got=%q", want, diagnostics[0].Error())
+	}
+}
+
+func TestFoldNegativeShift(t *testing.T) {
+	program := parse(t, "1 << (1 - 2);")
+
+	folded, diagnostics := Fold(program)
+
+	if got := folded.String(); got != "(1 << -1)" {
+		t.Errorf("negative shift should not be folded, got=%q", got)
+	}
+
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic, got=%v", diagnostics)
+	}
+
+	want := "1:3: negative shift count in (1 << -1)"
+	if diagnostics[0].Error() != want {
+		t.Errorf("wrong diagnostic, want=%q got=%q", want, diagnostics[0].Error())
+	}
+}
+
+func TestFoldFloatDivisionByZero(t *testing.T) {
+	program := parse(t, "1.5 / (2 - 2.0);")
+
+	folded, diagnostics := Fold(program)
+
+	if got := folded.String(); got != "(1.5 / 0.0)" {
+		t.Errorf("division by zero should not be folded, got=%q", got)
+	}
+
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic, got=%v", diagnostics)
+	}
+
+	want := "1:5: division by zero in (1.5 / 0.0)"
+	if diagnostics[0].Error() != want {
+		t.Errorf("wrong diagnostic, want=%q got=%q", want, diagnostics[0].Error())
+	}
+}
@@ -5,13 +5,17 @@ import (
 	"github.com/juanfgarcia/gorilla/lexer"
 	"github.com/juanfgarcia/gorilla/token"
 
+	"errors"
 	"fmt"
+	"math/big"
 	"strconv"
 )
 
 const (
 	_ int = iota
 	LOWEST
+	OR
+	AND
 	EQUALS
 	LESSGREATER
 	SUM
@@ -20,15 +24,39 @@ const (
 	CALL
 )
 
+// MaxDepth bounds how deeply expressions, patterns and loops may nest,
+// so that hostile input cannot exhaust the stack.
+const MaxDepth = 1000
+
 var precedences = map[token.TokenType]int{
-	token.EQUALS:   EQUALS,
-	token.NEQUALS:  EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
+	token.OR:        OR,
+	token.AND:       AND,
+	token.EQUALS:    EQUALS,
+	token.NEQUALS:   EQUALS,
+	token.LT:        LESSGREATER,
+	token.GT:        LESSGREATER,
+	token.LTE:       LESSGREATER,
+	token.GTE:       LESSGREATER,
+	token.PLUS:      SUM,
+	token.MINUS:     SUM,
+	token.PIPE:      SUM,
+	token.CARET:     SUM,
+	token.SLASH:     PRODUCT,
+	token.ASTERISK:  PRODUCT,
+	token.PERCENT:   PRODUCT,
+	token.AMPERSAND: PRODUCT,
+	token.SHL:       PRODUCT,
+	token.SHR:       PRODUCT,
+}
+
+// assignOperators are the tokens that may follow the name in an
+// assignment.
+var assignOperators = map[token.TokenType]bool{
+	token.ASSIGN:         true,
+	token.PLUSASSIGN:     true,
+	token.MINUSASSIGN:    true,
+	token.ASTERISKASSIGN: true,
+	token.SLASHASSIGN:    true,
 }
 
 type (
@@ -36,13 +64,38 @@ type (
 	infixParseFn  func(ast.Expression) ast.Expression
 )
 
+// Error is a parse error located at the token where it was detected.
+type Error struct {
+	Token token.Token
+	Msg   string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Token.Line, e.Token.Column, e.Msg)
+}
+
 type Parser struct {
 	l      *lexer.Lexer
-	errors []string
+	errors []Error
 
 	curToken  token.Token
 	peekToken token.Token
 
+	// blockDepth counts the blocks enclosing the current token.
+	blockDepth int
+
+	// depth counts the nested calls to parseExpression, parsePattern
+	// and the loop statements.
+	depth int
+
+	// loopDepth counts the loops enclosing the current token within
+	// the current function body.
+	loopDepth int
+
+	// scopes holds, for the program and each enclosing block, the
+	// names declared so far and the identifiers declaring them.
+	scopes []map[string]*ast.Identifier
+
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
 }
@@ -51,19 +104,24 @@ func New(input string) *Parser {
 	l := lexer.New(input)
 	p := &Parser{
 		l:      l,
-		errors: []string{},
+		errors: []Error{},
 	}
 
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.prefixParseFns[token.IDENTIFIER] = p.parseIdentifier
 	p.prefixParseFns[token.INT] = p.parseIntegerLiteral
+	p.prefixParseFns[token.FLOAT] = p.parseFloatLiteral
+	p.prefixParseFns[token.STRING] = p.parseStringLiteral
 	p.prefixParseFns[token.BANG] = p.parsePrefixExpression
 	p.prefixParseFns[token.MINUS] = p.parsePrefixExpression
+	p.prefixParseFns[token.TILDE] = p.parsePrefixExpression
 	p.prefixParseFns[token.TRUE] = p.parseBoolean
 	p.prefixParseFns[token.FALSE] = p.parseBoolean
 	p.prefixParseFns[token.LPAREN] = p.parseGroupedExpressions
 	p.prefixParseFns[token.IF] = p.parseIfExpression
 	p.prefixParseFns[token.FUNCTION] = p.parseFunctionLiteral
+	p.prefixParseFns[token.MACRO] = p.parseMacroLiteral
+	p.prefixParseFns[token.MATCH] = p.parseMatchExpression
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
 	p.infixParseFns[token.PLUS] = p.parseInfixExpression
@@ -74,6 +132,18 @@ func New(input string) *Parser {
 	p.infixParseFns[token.NEQUALS] = p.parseInfixExpression
 	p.infixParseFns[token.LT] = p.parseInfixExpression
 	p.infixParseFns[token.GT] = p.parseInfixExpression
+	p.infixParseFns[token.LTE] = p.parseInfixExpression
+	p.infixParseFns[token.GTE] = p.parseInfixExpression
+	p.infixParseFns[token.PERCENT] = p.parseInfixExpression
+	p.infixParseFns[token.AND] = p.parseInfixExpression
+	p.infixParseFns[token.OR] = p.parseInfixExpression
+	p.infixParseFns[token.AMPERSAND] = p.parseInfixExpression
+	p.infixParseFns[token.PIPE] = p.parseInfixExpression
+	p.infixParseFns[token.CARET] = p.parseInfixExpression
+	p.infixParseFns[token.SHL] = p.parseInfixExpression
+	p.infixParseFns[token.SHR] = p.parseInfixExpression
+
+	p.openScope()
 
 	p.NextToken()
 	p.NextToken()
@@ -95,14 +165,26 @@ func (p *Parser) curPrecedence() int {
 	return LOWEST
 }
 
+// Errors returns the parse errors formatted with their positions.
 func (p *Parser) Errors() []string {
+	msgs := make([]string, len(p.errors))
+	for i, err := range p.errors {
+		msgs[i] = err.Error()
+	}
+	return msgs
+}
+
+// Diagnostics returns the parse errors with the tokens they refer to.
+func (p *Parser) Diagnostics() []Error {
 	return p.errors
 }
 
-func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekToken)
+func (p *Parser) errorf(tok token.Token, format string, args ...interface{}) {
+	p.errors = append(p.errors, Error{Token: tok, Msg: fmt.Sprintf(format, args...)})
+}
 
-	p.errors = append(p.errors, msg)
+func (p *Parser) peekError(t token.TokenType) {
+	p.errorf(p.peekToken, "expected next token to be %s, got %s instead", t, p.peekToken.Typ)
 }
 
 func (p *Parser) NextToken() {
@@ -127,9 +209,45 @@ func (p *Parser) ParseProgram() *ast.Program {
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Typ {
 	case token.LET:
-		return p.parseLetStatement()
+		// Avoid wrapping a nil *ast.LetStatement in a non-nil Statement.
+		if stmt := p.parseLetStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.IMPORT:
+		if stmt := p.parseImportStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
+	case token.EXPORT:
+		if stmt := p.parseExportStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
+	case token.WHILE:
+		if stmt := p.parseWhileStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
+	case token.FOR:
+		if stmt := p.parseForStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
+	case token.IDENTIFIER:
+		if p.peekToken.Typ == token.DECLARE {
+			return p.parseDeclareStatement()
+		}
+		if assignOperators[p.peekToken.Typ] {
+			return p.parseAssignStatement()
+		}
+		return p.parseExpressionStatement()
+	case token.BREAK:
+		return p.parseBreakStatement()
+	case token.CONTINUE:
+		return p.parseContinueStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -147,10 +265,22 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 }
 
 func (p *Parser) parseExpression(precedence int) ast.Expression {
+	p.depth++
+	defer func() { p.depth-- }()
+
+	if p.depth > MaxDepth {
+		p.errorf(p.curToken, "expression nested too deeply")
+		return nil
+	}
+
 	prefix := p.prefixParseFns[p.curToken.Typ]
 
 	if prefix == nil {
-		p.errors = append(p.errors, fmt.Sprintf("no prefix parse function for %s found", p.curToken.Literal))
+		if p.curToken.Typ == token.ILLEGAL {
+			p.errorf(p.curToken, "illegal token %q", p.curToken.Literal)
+		} else {
+			p.errorf(p.curToken, "no prefix parse function for %s found", p.curToken.Literal)
+		}
 		return nil
 	}
 	leftExp := prefix()
@@ -159,7 +289,7 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 		infix := p.infixParseFns[p.peekToken.Typ]
 
 		if infix == nil {
-			p.errors = append(p.errors, fmt.Sprintf("no infix parse function for %s found", p.curToken.Literal))
+			p.errorf(p.peekToken, "no infix parse function for %s found", p.peekToken.Literal)
 			return leftExp
 		}
 
@@ -203,10 +333,23 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expression
 }
 
-func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+// parseBlockStatement parses a block in a scope of its own, in which
+// params, the parameters of a function or macro, are declared.
+func (p *Parser) parseBlockStatement(params ...*ast.Identifier) *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Statement{}
 
+	p.blockDepth++
+	p.openScope()
+	defer func() {
+		p.closeScope()
+		p.blockDepth--
+	}()
+
+	for _, param := range params {
+		p.declareNew(param)
+	}
+
 	p.NextToken()
 
 	for p.curToken.Typ != token.RBRACE && p.curToken.Typ != token.EOF {
@@ -262,13 +405,46 @@ func (p *Parser) parseIdentifier() ast.Expression {
 	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+// parseIntegerLiteral returns an IntegerLiteral, or a BigIntegerLiteral
+// when the value does not fit in an int64.
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		if n, ok := new(big.Int).SetString(p.curToken.Literal, 0); ok {
+			return &ast.BigIntegerLiteral{Token: p.curToken, Value: n}
+		}
+	}
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken, "could not parse %q as integer", p.curToken.Literal)
+	}
+	lit.Value = value
+
+	return lit
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil {
+		// Out of range values come back as an infinity, which has no
+		// literal and cannot be encoded as JSON.
+		p.errorf(p.curToken, "could not parse %q as float", p.curToken.Literal)
+		return lit
+	}
+	lit.Value = value
+
+	return lit
+}
+
+func (p *Parser) parseStringLiteral() ast.Expression {
+	lit := &ast.StringLiteral{Token: p.curToken}
+
+	value, err := strconv.Unquote(p.curToken.Literal)
+	if err != nil {
+		p.errorf(p.curToken, "could not parse %s as string", p.curToken.Literal)
 	}
 	lit.Value = value
 
@@ -288,11 +464,40 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
-	lit.Body = p.parseBlockStatement()
+	lit.Body = p.parseFunctionBody(lit.Parameters)
 
 	return lit
 }
 
+func (p *Parser) parseMacroLiteral() ast.Expression {
+	lit := &ast.MacroLiteral{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	lit.Parameters = p.parseFunctionParameters()
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	lit.Body = p.parseFunctionBody(lit.Parameters)
+
+	return lit
+}
+
+// parseFunctionBody parses the body of a function or macro, where
+// break and continue cannot reach the loops around the literal and
+// params share the scope of the body.
+func (p *Parser) parseFunctionBody(params []*ast.Identifier) *ast.BlockStatement {
+	loopDepth := p.loopDepth
+	p.loopDepth = 0
+	defer func() { p.loopDepth = loopDepth }()
+
+	return p.parseBlockStatement(params...)
+}
+
 func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	identifiers := []*ast.Identifier{}
 
@@ -301,14 +506,18 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 		return identifiers
 	}
 
-	p.NextToken()
+	if !p.expectPeek(token.IDENTIFIER) {
+		return nil
+	}
 
 	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	identifiers = append(identifiers, ident)
 
 	for p.peekToken.Typ == token.COMMA {
 		p.NextToken()
-		p.NextToken()
+		if !p.expectPeek(token.IDENTIFIER) {
+			return nil
+		}
 		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 		identifiers = append(identifiers, ident)
 	}
@@ -330,9 +539,16 @@ func (p *Parser) parseBoolean() ast.Expression {
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
 
-	//TODO: parseExpression, by the moment skip until semicolon
+	if p.peekToken.Typ == token.SEMICOLON {
+		p.NextToken()
+		return stmt
+	}
+
+	p.NextToken()
+
+	stmt.ReturnValue = p.parseExpression(LOWEST)
 
-	for p.curToken.Typ != token.SEMICOLON {
+	if p.peekToken.Typ == token.SEMICOLON {
 		p.NextToken()
 	}
 
@@ -347,20 +563,144 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	}
 
 	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	p.declare(stmt.Name)
 
-	if !p.expectPeek(token.ASSIGN) {
+	// let a := 3 was a let statement before := had a token of its own.
+	if p.peekToken.Typ == token.DECLARE {
+		p.NextToken()
+	} else if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
 
-	//TODO: parseExpression, by the moment skip until semicolon
+	p.NextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekToken.Typ == token.SEMICOLON {
+		p.NextToken()
+	}
+
+	return stmt
+}
+
+// parseDeclareStatement parses x := value. Unlike let, which may bind a
+// name again, := reports a name already declared in the same scope.
+func (p *Parser) parseDeclareStatement() *ast.DeclareStatement {
+	name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	p.NextToken()
+
+	stmt := &ast.DeclareStatement{Token: p.curToken, Name: name}
+
+	p.NextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	p.declareNew(name)
 
-	for p.curToken.Typ != token.SEMICOLON {
+	if p.peekToken.Typ == token.SEMICOLON {
 		p.NextToken()
 	}
 
 	return stmt
 }
 
+func (p *Parser) openScope() {
+	p.scopes = append(p.scopes, map[string]*ast.Identifier{})
+}
+
+func (p *Parser) closeScope() {
+	p.scopes = p.scopes[:len(p.scopes)-1]
+}
+
+// declare records ident in the innermost scope.
+func (p *Parser) declare(ident *ast.Identifier) {
+	p.scopes[len(p.scopes)-1][ident.Value] = ident
+}
+
+// declareNew records ident like declare, but reports a name already
+// declared in the innermost scope.
+func (p *Parser) declareNew(ident *ast.Identifier) {
+	if prev, ok := p.scopes[len(p.scopes)-1][ident.Value]; ok {
+		p.errorf(ident.Token, "%s already declared at %d:%d", ident.Value, prev.Token.Line, prev.Token.Column)
+	}
+	p.declare(ident)
+}
+
+// declared reports whether name is declared in any enclosing scope.
+func (p *Parser) declared(name string) bool {
+	for i := len(p.scopes) - 1; i >= 0; i-- {
+		if _, ok := p.scopes[i][name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAssignStatement parses x = value and its compound forms. Only a
+// name declared in this file can be assigned: names bound by imported
+// modules are read only to their importers.
+func (p *Parser) parseAssignStatement() *ast.AssignStatement {
+	name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.declared(name.Value) {
+		p.errorf(name.Token, "%s is not declared", name.Value)
+	}
+
+	p.NextToken()
+
+	stmt := &ast.AssignStatement{Token: p.curToken, Name: name, Operator: p.curToken.Literal}
+
+	p.NextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekToken.Typ == token.SEMICOLON {
+		p.NextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseImportStatement() *ast.ImportStatement {
+	stmt := &ast.ImportStatement{Token: p.curToken}
+
+	if p.blockDepth > 0 {
+		p.errorf(p.curToken, "import is only allowed at the top level")
+	}
+
+	if !p.expectPeek(token.STRING) {
+		return nil
+	}
+
+	stmt.Path = p.parseStringLiteral().(*ast.StringLiteral)
+
+	if p.peekToken.Typ == token.SEMICOLON {
+		p.NextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseExportStatement() *ast.ExportStatement {
+	stmt := &ast.ExportStatement{Token: p.curToken}
+
+	if p.blockDepth > 0 {
+		p.errorf(p.curToken, "export is only allowed at the top level")
+	}
+
+	if !p.expectPeek(token.LET) {
+		return nil
+	}
+
+	stmt.Statement = p.parseLetStatement()
+	if stmt.Statement == nil {
+		return nil
+	}
+
+	return stmt
+}
+
 func (p *Parser) expectPeek(t token.TokenType) bool {
 	if p.peekToken.Typ == t {
 		p.NextToken()
@@ -370,3 +710,305 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 		return false
 	}
 }
+
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	p.depth++
+	defer func() { p.depth-- }()
+
+	if p.depth > MaxDepth {
+		p.errorf(p.curToken, "loop nested too deeply")
+		return nil
+	}
+
+	stmt := &ast.WhileStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.NextToken()
+
+	stmt.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
+
+	return stmt
+}
+
+func (p *Parser) parseForStatement() *ast.ForStatement {
+	p.depth++
+	defer func() { p.depth-- }()
+
+	if p.depth > MaxDepth {
+		p.errorf(p.curToken, "loop nested too deeply")
+		return nil
+	}
+
+	stmt := &ast.ForStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.IDENTIFIER) {
+		return nil
+	}
+
+	stmt.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+	p.NextToken()
+
+	stmt.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	// The variable has a scope of its own around the body, so that the
+	// body may declare the name again.
+	p.openScope()
+	p.declare(stmt.Variable)
+	stmt.Body = p.parseLoopBody()
+	p.closeScope()
+
+	return stmt
+}
+
+// parseLoopBody parses the block of a loop, and the semicolon that may
+// follow it.
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	p.loopDepth++
+	body := p.parseBlockStatement()
+	p.loopDepth--
+
+	if p.peekToken.Typ == token.SEMICOLON {
+		p.NextToken()
+	}
+
+	return body
+}
+
+func (p *Parser) parseBreakStatement() *ast.BreakStatement {
+	stmt := &ast.BreakStatement{Token: p.curToken}
+
+	if p.loopDepth == 0 {
+		p.errorf(p.curToken, "break is only allowed inside a loop")
+	}
+
+	if p.peekToken.Typ == token.SEMICOLON {
+		p.NextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseContinueStatement() *ast.ContinueStatement {
+	stmt := &ast.ContinueStatement{Token: p.curToken}
+
+	if p.loopDepth == 0 {
+		p.errorf(p.curToken, "continue is only allowed inside a loop")
+	}
+
+	if p.peekToken.Typ == token.SEMICOLON {
+		p.NextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseMatchExpression() ast.Expression {
+	exp := &ast.MatchExpression{Token: p.curToken, Arms: []*ast.MatchArm{}}
+
+	p.NextToken()
+
+	exp.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for p.peekToken.Typ != token.RBRACE {
+		p.NextToken()
+
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		exp.Arms = append(exp.Arms, arm)
+
+		if p.peekToken.Typ != token.RBRACE && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.NextToken()
+
+	return exp
+}
+
+// parseMatchArm parses an arm in a scope of its own, in which the names
+// bound by the pattern are declared for the guard and the body.
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	arm := &ast.MatchArm{Token: p.curToken}
+
+	p.openScope()
+	defer p.closeScope()
+
+	arm.Pattern = p.parsePattern()
+	if arm.Pattern == nil {
+		return nil
+	}
+
+	if p.peekToken.Typ == token.IF {
+		p.NextToken()
+		p.NextToken()
+		arm.Guard = p.parseExpression(LOWEST)
+	}
+
+	if !p.expectPeek(token.FATARROW) {
+		return nil
+	}
+	p.NextToken()
+
+	arm.Body = p.parseExpression(LOWEST)
+
+	return arm
+}
+
+// parsePattern parses the pattern starting at the current token: _, a
+// name, a literal, possibly negative, or an array or hash pattern.
+func (p *Parser) parsePattern() ast.Pattern {
+	p.depth++
+	defer func() { p.depth-- }()
+
+	if p.depth > MaxDepth {
+		p.errorf(p.curToken, "pattern nested too deeply")
+		return nil
+	}
+
+	switch p.curToken.Typ {
+	case token.UNDERSCORE:
+		return &ast.WildcardPattern{Token: p.curToken}
+	case token.IDENTIFIER:
+		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		p.declareNew(ident)
+		return ident
+	case token.INT, token.FLOAT, token.STRING, token.TRUE, token.FALSE:
+		return p.parseLiteralPattern()
+	case token.MINUS:
+		if p.peekToken.Typ == token.INT || p.peekToken.Typ == token.FLOAT {
+			// Read -5 as a single literal, located at the minus.
+			minus := p.curToken
+			p.NextToken()
+			p.curToken.Literal = minus.Literal + p.curToken.Literal
+			p.curToken.Line, p.curToken.Column = minus.Line, minus.Column
+			return p.parseLiteralPattern()
+		}
+	case token.LBRACKET:
+		if pattern := p.parseArrayPattern(); pattern != nil {
+			return pattern
+		}
+		return nil
+	case token.LBRACE:
+		if pattern := p.parseHashPattern(); pattern != nil {
+			return pattern
+		}
+		return nil
+	}
+
+	p.errorf(p.curToken, "expected a pattern, got %s", p.curToken.Typ)
+	return nil
+}
+
+// parseLiteralPattern parses the current literal token, whose parse
+// function returns a node that is also a Pattern.
+func (p *Parser) parseLiteralPattern() ast.Pattern {
+	pattern, _ := p.prefixParseFns[p.curToken.Typ]().(ast.Pattern)
+	return pattern
+}
+
+func (p *Parser) parseArrayPattern() *ast.ArrayPattern {
+	pattern := &ast.ArrayPattern{Token: p.curToken, Elements: []ast.Pattern{}}
+
+	for p.peekToken.Typ != token.RBRACKET {
+		p.NextToken()
+
+		if p.curToken.Typ == token.ELLIPSIS {
+			p.NextToken()
+
+			switch p.curToken.Typ {
+			case token.IDENTIFIER, token.UNDERSCORE:
+				pattern.Rest = p.parsePattern()
+			default:
+				p.errorf(p.curToken, "expected a name or _ after ..., got %s", p.curToken.Typ)
+				return nil
+			}
+
+			// The rest must come last.
+			if !p.expectPeek(token.RBRACKET) {
+				return nil
+			}
+			return pattern
+		}
+
+		element := p.parsePattern()
+		if element == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, element)
+
+		if p.peekToken.Typ != token.RBRACKET && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.NextToken()
+
+	return pattern
+}
+
+func (p *Parser) parseHashPattern() *ast.HashPattern {
+	pattern := &ast.HashPattern{Token: p.curToken, Keys: []ast.Expression{}, Values: []ast.Pattern{}}
+
+	for p.peekToken.Typ != token.RBRACE {
+		p.NextToken()
+
+		switch p.curToken.Typ {
+		case token.STRING, token.INT, token.TRUE, token.FALSE:
+			pattern.Keys = append(pattern.Keys, p.prefixParseFns[p.curToken.Typ]())
+		default:
+			p.errorf(p.curToken, "expected a literal hash key, got %s", p.curToken.Typ)
+			return nil
+		}
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+		p.NextToken()
+
+		value := p.parsePattern()
+		if value == nil {
+			return nil
+		}
+		pattern.Values = append(pattern.Values, value)
+
+		if p.peekToken.Typ != token.RBRACE && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.NextToken()
+
+	return pattern
+}
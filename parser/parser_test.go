@@ -3,7 +3,10 @@ package parser
 import (
 	"fmt"
 	"github.com/juanfgarcia/gorilla/ast"
+	"math"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLetStatements(t *testing.T) {
@@ -30,6 +33,166 @@ let a  = 23;`
 	}
 }
 
+func TestLetDeclareStatement(t *testing.T) {
+	p := New(`let a := 3;`)
+	program := p.ParseProgram()
+
+	AssertNoErrors(t, p)
+	AssertNumberStatements(t, len(program.Statements), 1)
+	AssertLetStmt(t, program.Statements[0], "a")
+
+	stmt := program.Statements[0].(*ast.LetStatement)
+	AssertLiteralExpression(t, stmt.Value, 3)
+}
+
+func TestInvalidLetStatement(t *testing.T) {
+	p := New(`let = 5;`)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) == 0 {
+		t.Fatalf("expected parse errors")
+	}
+
+	for i, stmt := range program.Statements {
+		if stmt, ok := stmt.(*ast.LetStatement); ok && stmt == nil {
+			t.Errorf("program.Statements[%d] is a nil *ast.LetStatement", i)
+		}
+	}
+}
+
+func TestDeclareStatements(t *testing.T) {
+	p := New("x := 5; y := x + 1")
+	program := p.ParseProgram()
+	AssertNoErrors(t, p)
+	AssertNumberStatements(t, len(program.Statements), 2)
+
+	stmt, ok := program.Statements[0].(*ast.DeclareStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.DeclareStatement. got=%T", program.Statements[0])
+	}
+
+	if stmt.TokenLiteral() != ":=" {
+		t.Errorf("stmt.TokenLiteral not ':='. got=%q", stmt.TokenLiteral())
+	}
+	AssertIdentifier(t, stmt.Name, "x")
+	AssertLiteralExpression(t, stmt.Value, 5)
+
+	if got := program.String(); got != "x := 5;y := (x + 1);" {
+		t.Errorf("wrong program, got=%q", got)
+	}
+}
+
+func TestRedeclaration(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"x := 1; x := 2;", "1:9: x already declared at 1:1"},
+		{"let x = 1;\nx := 2;", "2:1: x already declared at 1:5"},
+		{"if (true) { a := 1; b := 2; a := 3 }", "1:29: a already declared at 1:13"},
+		{"x := 1; if (true) { x := 2; }", ""},
+		{"x := 1; fn(x) { x := x; }", "1:17: x already declared at 1:12"},
+		{"macro(a, b) { b := a; }", "1:15: b already declared at 1:10"},
+		{"fn(x) { if (true) { x := 1; } }", ""},
+		{"fn(a, a) { a }", "1:7: a already declared at 1:4"},
+		{"macro(a, a) {}", "1:10: a already declared at 1:7"},
+		{"for (x in xs) { x := 1; }", ""},
+		{"while (true) { x := 1; } x := 2;", ""},
+		{"x := 1; let x = 2; x = 3;", ""},
+	}
+
+	for _, tt := range tests {
+		p := New(tt.input)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if tt.err == "" {
+			if len(errors) != 0 {
+				t.Errorf("unexpected errors for %q: %v", tt.input, errors)
+			}
+			continue
+		}
+		if len(errors) != 1 || errors[0] != tt.err {
+			t.Errorf("wrong errors for %q, want=%q got=%q", tt.input, tt.err, errors)
+		}
+	}
+}
+
+func TestAssignStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		name     string
+		operator string
+		value    interface{}
+	}{
+		{"x = 5;", "x", "=", 5},
+		{"x += 1", "x", "+=", 1},
+		{"total -= y;", "total", "-=", "y"},
+		{"x *= true;", "x", "*=", true},
+		{"x /= 2;", "x", "/=", 2},
+	}
+
+	for _, tt := range tests {
+		p := New("let x = 0; let total = 0;\n" + tt.input)
+		program := p.ParseProgram()
+		AssertNoErrors(t, p)
+		AssertNumberStatements(t, len(program.Statements), 3)
+
+		stmt, ok := program.Statements[2].(*ast.AssignStatement)
+		if !ok {
+			t.Fatalf("program.Statements[2] is not ast.AssignStatement. got=%T", program.Statements[2])
+		}
+
+		AssertIdentifier(t, stmt.Name, tt.name)
+		if stmt.Operator != tt.operator {
+			t.Errorf("stmt.Operator is not '%s'. got=%s", tt.operator, stmt.Operator)
+		}
+		AssertLiteralExpression(t, stmt.Value, tt.value)
+	}
+
+	p := New("let x = 0; x = x + 1; x;")
+	program := p.ParseProgram()
+	AssertNoErrors(t, p)
+
+	if got := program.String(); got != "let x = 0;x = (x + 1);x" {
+		t.Errorf("wrong program, got=%q", got)
+	}
+}
+
+func TestAssignUndeclared(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"x = 1;", "1:1: x is not declared"},
+		{"x += 1;", "1:1: x is not declared"},
+		{"x = 1; let x = 2;", "1:1: x is not declared"},
+		{"if (true) { let y = 1; } y = 2;", "1:26: y is not declared"},
+		{"let x = 1; fn() { x += 1; }", ""},
+		{"fn(x) { x = 2; }", ""},
+		{"for (i in xs) { i = i + 1; }", ""},
+		{"let f = fn() { f = 1; };", ""},
+		{"match x { [n] => fn() { n += 1; } }", ""},
+		{"match x { n => 1, _ => fn() { n = 1; } }", "1:31: n is not declared"},
+	}
+
+	for _, tt := range tests {
+		p := New(tt.input)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if tt.err == "" {
+			if len(errors) != 0 {
+				t.Errorf("unexpected errors for %q: %v", tt.input, errors)
+			}
+			continue
+		}
+		if len(errors) != 1 || errors[0] != tt.err {
+			t.Errorf("wrong errors for %q, want=%q got=%q", tt.input, tt.err, errors)
+		}
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	input := `return 5;
     return 123;
@@ -54,6 +217,207 @@ func TestReturnStatements(t *testing.T) {
 	}
 }
 
+func TestStringLiteral(t *testing.T) {
+	input := `"hello \"world\"";`
+
+	p := New(input)
+	program := p.ParseProgram()
+	AssertNoErrors(t, p)
+
+	AssertNumberStatements(t, len(program.Statements), 1)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	lit, ok := stmt.Expression.(*ast.StringLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+	}
+
+	if lit.Value != `hello "world"` {
+		t.Errorf("literal.Value wrong, got=%q", lit.Value)
+	}
+}
+
+func TestIntegerLiteralBases(t *testing.T) {
+	tests := []struct {
+		input string
+		value int64
+	}{
+		{"0xFF;", 255},
+		{"0Xff;", 255},
+		{"0b1010;", 10},
+		{"0o17;", 15},
+		{"1_000_000;", 1000000},
+		{"0xdead_beef;", 0xdeadbeef},
+	}
+
+	for _, tt := range tests {
+		p := New(tt.input)
+		program := p.ParseProgram()
+		AssertNoErrors(t, p)
+
+		AssertNumberStatements(t, len(program.Statements), 1)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		lit, ok := stmt.Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+		}
+		if lit.Value != tt.value {
+			t.Errorf("%q: literal.Value not %d. got=%d", tt.input, tt.value, lit.Value)
+		}
+	}
+}
+
+func TestMalformedIntegerLiteral(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"0b2;", `1:1: could not parse "0b2" as integer`},
+		{"x + 1_000_;", `1:5: could not parse "1_000_" as integer`},
+		{"1__0;", `1:1: could not parse "1__0" as integer`},
+		{"let x =\n  0x;", `2:3: could not parse "0x" as integer`},
+		{"0xFG;", `1:1: could not parse "0xFG" as integer`},
+	}
+
+	for _, tt := range tests {
+		p := New(tt.input)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Errorf("%q: expected 1 error, got=%v", tt.input, errors)
+			continue
+		}
+		if errors[0] != tt.want {
+			t.Errorf("%q: wrong error, want=%q got=%q", tt.input, tt.want, errors[0])
+		}
+	}
+}
+
+func TestFloatLiteral(t *testing.T) {
+	tests := []struct {
+		input string
+		value float64
+	}{
+		{"3.14;", 3.14},
+		{"1e9;", 1e9},
+		{"2.5E-3;", 2.5e-3},
+	}
+
+	for _, tt := range tests {
+		p := New(tt.input)
+		program := p.ParseProgram()
+		AssertNoErrors(t, p)
+
+		AssertNumberStatements(t, len(program.Statements), 1)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		lit, ok := stmt.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+		}
+
+		if lit.Value != tt.value {
+			t.Errorf("literal.Value not %g. got=%g", tt.value, lit.Value)
+		}
+	}
+}
+
+func TestFloatOutOfRange(t *testing.T) {
+	p := New("1e999;")
+	program := p.ParseProgram()
+
+	errors := p.Errors()
+	want := `1:1: could not parse "1e999" as float`
+	if len(errors) != 1 || errors[0] != want {
+		t.Fatalf("wrong errors, want=%q got=%q", want, errors)
+	}
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	if lit := stmt.Expression.(*ast.FloatLiteral); lit.Value != 0 {
+		t.Errorf("literal.Value not 0. got=%g", lit.Value)
+	}
+
+	if _, err := ast.MarshalJSON(program); err != nil {
+		t.Errorf("MarshalJSON failed: %v", err)
+	}
+}
+
+func TestBigIntegerLiteral(t *testing.T) {
+	input := "9223372036854775807; 9223372036854775808; 123456789012345678901234567890;"
+
+	p := New(input)
+	program := p.ParseProgram()
+	AssertNoErrors(t, p)
+
+	AssertNumberStatements(t, len(program.Statements), 3)
+
+	AssertIntegerLiteral(t, program.Statements[0].(*ast.ExpressionStatement).Expression, math.MaxInt64)
+
+	for i, want := range []string{"9223372036854775808", "123456789012345678901234567890"} {
+		stmt := program.Statements[i+1].(*ast.ExpressionStatement)
+		lit, ok := stmt.Expression.(*ast.BigIntegerLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.BigIntegerLiteral. got=%T", stmt.Expression)
+		}
+		if lit.Value.String() != want {
+			t.Errorf("literal.Value not %s. got=%s", want, lit.Value)
+		}
+	}
+}
+
+func TestImportExportStatements(t *testing.T) {
+	input := `import "lib/math";
+import "strings"
+export let x = 5;`
+
+	p := New(input)
+	program := p.ParseProgram()
+	AssertNoErrors(t, p)
+
+	AssertNumberStatements(t, len(program.Statements), 3)
+
+	for i, path := range []string{"lib/math", "strings"} {
+		stmt, ok := program.Statements[i].(*ast.ImportStatement)
+		if !ok {
+			t.Fatalf("program.Statements[%d] is not ast.ImportStatement. got=%T", i, program.Statements[i])
+		}
+		if stmt.Path.Value != path {
+			t.Errorf("stmt.Path.Value not %q. got=%q", path, stmt.Path.Value)
+		}
+	}
+
+	export, ok := program.Statements[2].(*ast.ExportStatement)
+	if !ok {
+		t.Fatalf("program.Statements[2] is not ast.ExportStatement. got=%T", program.Statements[2])
+	}
+
+	AssertLetStmt(t, export.Statement, "x")
+}
+
+func TestNestedImportExport(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{`fn() { import "x"; }`, "1:8: import is only allowed at the top level"},
+		{`if (true) { export let x = 1; }`, "1:13: export is only allowed at the top level"},
+		{`export 5;`, "1:8: expected next token to be LET, got INT instead"},
+		{`import x;`, "1:8: expected next token to be STRING, got IDENTIFIER instead"},
+	}
+
+	for _, tt := range tests {
+		p := New(tt.input)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.err {
+			t.Errorf("wrong errors for %q, want=%q got=%q", tt.input, tt.err, errors)
+		}
+	}
+}
+
 func TestPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input    string
@@ -62,6 +426,7 @@ func TestPrefixExpressions(t *testing.T) {
 	}{
 		{"!5;", "!", 5},
 		{"-15;", "-", 15},
+		{"~15;", "~", 15},
 		{"!false;", "!", false},
 	}
 
@@ -140,6 +505,16 @@ func TestInfixExpression(t *testing.T) {
 		{"5 < 5;", 5, "<", 5},
 		{"5 == 5;", 5, "==", 5},
 		{"5 != 5;", 5, "!=", 5},
+		{"5 <= 5;", 5, "<=", 5},
+		{"5 >= 5;", 5, ">=", 5},
+		{"5 % 5;", 5, "%", 5},
+		{"true && false;", true, "&&", false},
+		{"true || false;", true, "||", false},
+		{"5 & 5;", 5, "&", 5},
+		{"5 | 5;", 5, "|", 5},
+		{"5 ^ 5;", 5, "^", 5},
+		{"5 << 5;", 5, "<<", 5},
+		{"5 >> 5;", 5, ">>", 5},
 		{"true == true;", true, "==", true},
 		{"true != false;", true, "!=", false},
 		{"false == false;", false, "==", false},
@@ -191,6 +566,18 @@ func TestOperatorPrecedence(t *testing.T) {
 		{"a + b / c;", "(a + (b / c))"},
 		{" 3 > 5 == false;", "((3 > 5) == false)"},
 		{"(2+3) * 3;", "((2 + 3) * 3)"},
+		{"a + b % c * d;", "(a + ((b % c) * d))"},
+		{"a <= b == c >= d;", "((a <= b) == (c >= d))"},
+		{"a == b && c != d;", "((a == b) && (c != d))"},
+		{"a || b && c;", "(a || (b && c))"},
+		{"a && b || c && d;", "((a && b) || (c && d))"},
+		{"!a && -b < c;", "((!a) && ((-b) < c))"},
+		{"a | b & c;", "(a | (b & c))"},
+		{"a ^ b << 2;", "(a ^ (b << 2))"},
+		{"a + b >> c;", "(a + (b >> c))"},
+		{"a & b == c;", "((a & b) == c)"},
+		{"a | b < c ^ d;", "((a | b) < (c ^ d))"},
+		{"~a & -b;", "((~a) & (-b))"},
 	}
 
 	for _, tt := range tests {
@@ -317,6 +704,381 @@ func TestFunctionLiteral(t *testing.T) {
 
 }
 
+func TestMacroLiteral(t *testing.T) {
+	input := `macro(x, y) { x + y; }`
+
+	p := New(input)
+
+	program := p.ParseProgram()
+	AssertNoErrors(t, p)
+	AssertNumberStatements(t, len(program.Statements), 1)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+
+	macro, ok := stmt.Expression.(*ast.MacroLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MacroLiteral. got=%T", stmt.Expression)
+	}
+
+	AssertNumberStatements(t, len(macro.Parameters), 2)
+
+	AssertLiteralExpression(t, macro.Parameters[0], "x")
+	AssertLiteralExpression(t, macro.Parameters[1], "y")
+
+	AssertNumberStatements(t, len(macro.Body.Statements), 1)
+
+	body, ok := macro.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("Statements[0] is not ast.ExpressionStatement, got=%T", macro.Body.Statements[0])
+	}
+
+	AssertInfixExpression(t, body.Expression, "x", "+", "y")
+}
+
+func TestWhileStatement(t *testing.T) {
+	input := `while (x < 10) { x; };`
+
+	p := New(input)
+	program := p.ParseProgram()
+	AssertNoErrors(t, p)
+	AssertNumberStatements(t, len(program.Statements), 1)
+
+	stmt, ok := program.Statements[0].(*ast.WhileStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.WhileStatement. got=%T", program.Statements[0])
+	}
+
+	AssertInfixExpression(t, stmt.Condition, "x", "<", 10)
+
+	AssertNumberStatements(t, len(stmt.Body.Statements), 1)
+
+	body, ok := stmt.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("Statements[0] is not ast.ExpressionStatement, got=%T", stmt.Body.Statements[0])
+	}
+	AssertIdentifier(t, body.Expression, "x")
+}
+
+func TestForStatement(t *testing.T) {
+	input := `for (x in xs) { x; }`
+
+	p := New(input)
+	program := p.ParseProgram()
+	AssertNoErrors(t, p)
+	AssertNumberStatements(t, len(program.Statements), 1)
+
+	stmt, ok := program.Statements[0].(*ast.ForStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ForStatement. got=%T", program.Statements[0])
+	}
+
+	AssertIdentifier(t, stmt.Variable, "x")
+	AssertIdentifier(t, stmt.Iterable, "xs")
+	AssertNumberStatements(t, len(stmt.Body.Statements), 1)
+}
+
+func TestBreakContinue(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{`while (true) { break; continue; }`, ""},
+		{`for (x in xs) { if (x) { break } else { continue } }`, ""},
+		{`while (a) { while (b) { break; } continue; }`, ""},
+		{`break;`, "1:1: break is only allowed inside a loop"},
+		{`if (x) { continue; }`, "1:10: continue is only allowed inside a loop"},
+		{`while (true) { fn() { break; }; }`, "1:23: break is only allowed inside a loop"},
+		{`while (true) { } continue;`, "1:18: continue is only allowed inside a loop"},
+		{`for x in xs { }`, "1:5: expected next token to be LPAREN, got IDENTIFIER instead"},
+		{`for (x, xs) { }`, "1:7: expected next token to be IN, got COMMA instead"},
+	}
+
+	for _, tt := range tests {
+		p := New(tt.input)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if tt.err == "" {
+			if len(errors) != 0 {
+				t.Errorf("unexpected errors for %q: %v", tt.input, errors)
+			}
+			continue
+		}
+		if len(errors) == 0 || errors[0] != tt.err {
+			t.Errorf("wrong errors for %q, want=%q got=%q", tt.input, tt.err, errors)
+		}
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	input := `match value { 0 => "zero", [h, ...t] => h, {"k": v} => v, n if n < -1 => -n, _ => "other" }`
+
+	p := New(input)
+	program := p.ParseProgram()
+	AssertNoErrors(t, p)
+	AssertNumberStatements(t, len(program.Statements), 1)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MatchExpression. got=%T", stmt.Expression)
+	}
+
+	AssertIdentifier(t, exp.Subject, "value")
+	AssertNumberStatements(t, len(exp.Arms), 5)
+
+	AssertIntegerLiteral(t, exp.Arms[0].Pattern.(ast.Expression), 0)
+
+	array, ok := exp.Arms[1].Pattern.(*ast.ArrayPattern)
+	if !ok {
+		t.Fatalf("Arms[1].Pattern is not ast.ArrayPattern. got=%T", exp.Arms[1].Pattern)
+	}
+	AssertNumberStatements(t, len(array.Elements), 1)
+	AssertIdentifier(t, array.Elements[0].(ast.Expression), "h")
+	AssertIdentifier(t, array.Rest.(ast.Expression), "t")
+
+	hash, ok := exp.Arms[2].Pattern.(*ast.HashPattern)
+	if !ok {
+		t.Fatalf("Arms[2].Pattern is not ast.HashPattern. got=%T", exp.Arms[2].Pattern)
+	}
+	if len(hash.Keys) != 1 || hash.Keys[0].String() != `"k"` {
+		t.Errorf("wrong hash pattern keys, got=%v", hash.Keys)
+	}
+	AssertIdentifier(t, hash.Values[0].(ast.Expression), "v")
+
+	AssertIdentifier(t, exp.Arms[3].Pattern.(ast.Expression), "n")
+	if exp.Arms[3].Guard == nil || exp.Arms[3].Guard.String() != "(n < (-1))" {
+		t.Errorf("wrong guard, got=%v", exp.Arms[3].Guard)
+	}
+
+	if _, ok := exp.Arms[4].Pattern.(*ast.WildcardPattern); !ok {
+		t.Errorf("Arms[4].Pattern is not ast.WildcardPattern. got=%T", exp.Arms[4].Pattern)
+	}
+	if exp.Arms[4].Guard != nil {
+		t.Errorf("Arms[4] should have no guard, got=%s", exp.Arms[4].Guard)
+	}
+
+	want := `match value { 0 => "zero", [h, ...t] => h, {"k": v} => v, n if (n < (-1)) => (-n), _ => "other" }`
+	if got := program.String(); got != want {
+		t.Errorf("wrong program,\nwant=%q\ngot= %q", want, got)
+	}
+}
+
+func TestMatchPatterns(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match x { -2.5 => 1 }", "match x { -2.5 => 1 }"},
+		{"match x { [] => 1, [_, [a]] => a, }", "match x { [] => 1, [_, [a]] => a }"},
+		{"match x { [..._] => 1 }", "match x { [..._] => 1 }"},
+		{`match x { {"a": [b], 1: _, true: c} => b }`, `match x { {"a": [b], 1: _, true: c} => b }`},
+		{"match x { }", "match x {  }"},
+		{"match match x { _ => y } { _ => z }", "match match x { _ => y } { _ => z }"},
+	}
+
+	for _, tt := range tests {
+		p := New(tt.input)
+		program := p.ParseProgram()
+		AssertNoErrors(t, p)
+
+		if got := program.String(); got != tt.expected {
+			t.Errorf("Want=%q, but got=%q", tt.expected, got)
+		}
+	}
+}
+
+func TestMatchErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"match x { 1 }", "1:13: expected next token to be FATARROW, got RBRACE instead"},
+		{"match x { + => 1 }", "1:11: expected a pattern, got PLUS"},
+		{"match x { -y => 1 }", "1:11: expected a pattern, got MINUS"},
+		{"match x { [...1] => 1 }", "1:15: expected a name or _ after ..., got INT"},
+		{"match x { [...t, u] => 1 }", "1:16: expected next token to be RBRACKET, got COMMA instead"},
+		{"match x { {k: v} => v }", "1:12: expected a literal hash key, got IDENTIFIER"},
+		{"match x { [a b] => a }", "1:14: expected next token to be COMMA, got IDENTIFIER instead"},
+		{"match x { 1 => 2", "1:17: expected next token to be COMMA, got EOF instead"},
+		{"match x { [a, a] => a }", "1:15: a already declared at 1:12"},
+	}
+
+	for _, tt := range tests {
+		p := New(tt.input)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.err {
+			t.Errorf("wrong errors for %q, want=%q got=%q", tt.input, tt.err, errors)
+		}
+	}
+}
+
+func TestErrorPositions(t *testing.T) {
+	input := `if (x < y) { x }
+if x { y }`
+
+	p := New(input)
+	p.ParseProgram()
+
+	errors := p.Diagnostics()
+	if len(errors) == 0 {
+		t.Fatalf("expected parse errors")
+	}
+
+	got := errors[0]
+	if got.Token.Line != 2 || got.Token.Column != 4 || got.Token.Literal != "x" {
+		t.Errorf("error located at wrong token, got=%+v", got.Token)
+	}
+
+	want := "2:4: expected next token to be LPAREN, got IDENTIFIER instead"
+	if p.Errors()[0] != want {
+		t.Errorf("Errors()[0] wrong, want=%q got=%q", want, p.Errors()[0])
+	}
+}
+
+func TestLetStatementValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 5;", "let x = 5;"},
+		{"let y = a + b * c;", "let y = (a + (b * c));"},
+		{"let f = fn(x) { x }", "let f = fn( x,  )x;"},
+		{"return 5;", "return 5;"},
+		{"return a + b", "return (a + b);"},
+		{"return;", "return ;"},
+	}
+
+	for _, tt := range tests {
+		p := New(tt.input)
+		program := p.ParseProgram()
+		AssertNoErrors(t, p)
+
+		if got := program.String(); got != tt.expected {
+			t.Errorf("Want=%q, but got=%q", tt.expected, got)
+		}
+	}
+}
+
+func TestTermination(t *testing.T) {
+	tests := []string{
+		"let x = 5",
+		"let x",
+		"let",
+		"return",
+		"return 5",
+		"fn() {",
+		"fn(x, 1) { x }",
+		"fn(",
+		"if (",
+		"if (@) { x }",
+		"1 +",
+		"-",
+		"@#$",
+		"\x00",
+		`"open`,
+		"}",
+		strings.Repeat("(", 100000),
+		strings.Repeat("-", 100000) + "x",
+		strings.Repeat("fn() { ", 10000),
+		strings.Repeat("if (x) { ", 10000),
+		strings.Repeat("while (x) { ", 10000),
+		strings.Repeat("for (x in y) { ", 10000),
+		"match",
+		"match x { [",
+		"match x { {",
+		"match x { [...",
+		"match x { " + strings.Repeat("[", 100000),
+		strings.Repeat("match x { _ => ", 10000),
+	}
+
+	for _, input := range tests {
+		AssertTerminates(t, input)
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	// The statement itself is one level, each parenthesis another.
+	p := New(strings.Repeat("(", MaxDepth-1) + "1" + strings.Repeat(")", MaxDepth-1))
+	p.ParseProgram()
+	AssertNoErrors(t, p)
+
+	p = New(strings.Repeat("(", MaxDepth) + "1" + strings.Repeat(")", MaxDepth))
+	p.ParseProgram()
+
+	errors := p.Errors()
+	want := fmt.Sprintf("1:%d: expression nested too deeply", MaxDepth+1)
+	if len(errors) == 0 || errors[0] != want {
+		t.Errorf("expected %q, got=%q", want, errors)
+	}
+}
+
+func TestMaxLoopDepth(t *testing.T) {
+	// The condition of the innermost loop is one level deeper still.
+	p := New(strings.Repeat("while (x) { ", MaxDepth-1) + strings.Repeat("}", MaxDepth-1))
+	p.ParseProgram()
+	AssertNoErrors(t, p)
+
+	p = New(strings.Repeat("for (x in y) { ", MaxDepth+1) + strings.Repeat("}", MaxDepth+1))
+	p.ParseProgram()
+
+	errors := p.Errors()
+	want := fmt.Sprintf("1:%d: loop nested too deeply", MaxDepth*len("for (x in y) { ")+1)
+	for _, err := range errors {
+		if err == want {
+			return
+		}
+	}
+	t.Errorf("expected %q, got=%q", want, errors)
+}
+
+// FuzzParseProgram checks that the parser terminates without
+// panicking on any input, and that the result can be printed. Only
+// parsing is bound to take linear time; printing may take quadratic.
+func FuzzParseProgram(f *testing.F) {
+	f.Add(`let x = 5; return x;`)
+	f.Add(`if (x < y) { x } else { y }`)
+	f.Add(`fn(x, y) { x + y; }`)
+	f.Add(`macro(a) { -a * !b }`)
+	f.Add(`import "lib"; export let a = "s";`)
+	f.Add(`while (x) { for (y in x) { x := y; x += 1; break; } }`)
+	f.Add(`while (x) { while (y) { for (z in y) { continue; } } }`)
+	f.Add(`match v { -1 => 0, [h, ...t] if h => t, {"k": _} => 1 }`)
+
+	f.Fuzz(func(t *testing.T, input string) {
+		p := New(input)
+		program := p.ParseProgram()
+		_ = program.String()
+	})
+}
+
+// AssertTerminates fails if parsing input does not finish within a few
+// seconds.
+func AssertTerminates(t testing.TB, input string) {
+	t.Helper()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		New(input).ParseProgram()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		name := input
+		if len(name) > 20 {
+			name = name[:20] + "..."
+		}
+		t.Fatalf("parsing %q did not terminate", name)
+	}
+}
+
 func AssertLiteralExpression(t testing.TB, exp ast.Expression, expected interface{}) {
 	t.Helper()
 
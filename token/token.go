@@ -1,10 +1,14 @@
 package token
 
+import "fmt"
+
 type TokenType int
 
 type Token struct {
-	Typ     TokenType
-	Literal string
+	Typ     TokenType `json:"type"`
+	Literal string    `json:"literal"`
+	Line    int       `json:"line"`
+	Column  int       `json:"column"`
 }
 
 const (
@@ -14,11 +18,18 @@ const (
 	// Identifiers
 	IDENTIFIER
 	INT
+	FLOAT
+	STRING
 	TRUE
 	FALSE
 
 	// Operators
 	ASSIGN
+	DECLARE
+	PLUSASSIGN
+	MINUSASSIGN
+	ASTERISKASSIGN
+	SLASHASSIGN
 	EQUALS
 	NEQUALS
 	PLUS
@@ -26,9 +37,23 @@ const (
 	ASTERISK
 	RIGHTARROW
 	SLASH
+	PERCENT
 	BANG
+	FATARROW
+	UNDERSCORE
+	ELLIPSIS
 	LT
 	GT
+	LTE
+	GTE
+	AND
+	OR
+	AMPERSAND
+	PIPE
+	CARET
+	TILDE
+	SHL
+	SHR
 
 	// Delimiters
 	COMMA
@@ -39,6 +64,8 @@ const (
 	RPAREN
 	LBRACE
 	RBRACE
+	LBRACKET
+	RBRACKET
 
 	// Keywords
 	TYPE
@@ -47,51 +74,124 @@ const (
 	RETURN
 	IF
 	ELSE
+	MACRO
+	IMPORT
+	EXPORT
+	WHILE
+	FOR
+	IN
+	BREAK
+	CONTINUE
+	MATCH
 )
 
+var names = [...]string{
+	"ILLEGAL",
+	"EOF",
+	"IDENTIFIER",
+	"INT",
+	"FLOAT",
+	"STRING",
+	"TRUE",
+	"FALSE",
+	"ASSIGN",
+	"DECLARE",
+	"PLUSASSIGN",
+	"MINUSASSIGN",
+	"ASTERISKASSIGN",
+	"SLASHASSIGN",
+	"EQUALS",
+	"NEQUALS",
+	"PLUS",
+	"MINUS",
+	"ASTERISK",
+	"RIGHTARROW",
+	"SLASH",
+	"PERCENT",
+	"BANG",
+	"FATARROW",
+	"UNDERSCORE",
+	"ELLIPSIS",
+	"LT",
+	"GT",
+	"LTE",
+	"GTE",
+	"AND",
+	"OR",
+	"AMPERSAND",
+	"PIPE",
+	"CARET",
+	"TILDE",
+	"SHL",
+	"SHR",
+	"COMMA",
+	"COLON",
+	"SEMICOLON",
+	"LPAREN",
+	"RPAREN",
+	"LBRACE",
+	"RBRACE",
+	"LBRACKET",
+	"RBRACKET",
+	"TYPE",
+	"LET",
+	"FUNCTION",
+	"RETURN",
+	"IF",
+	"ELSE",
+	"MACRO",
+	"IMPORT",
+	"EXPORT",
+	"WHILE",
+	"FOR",
+	"IN",
+	"BREAK",
+	"CONTINUE",
+	"MATCH",
+}
+
 func (t TokenType) String() string {
-	return [...]string{
-		"ILLEGAL",
-		"EOF",
-		"IDENTIFIER",
-		"INT",
-		"TRUE",
-		"FALSE",
-		"ASSIGN",
-		"EQUALS",
-		"NEQUALS",
-		"PLUS",
-		"MINUS",
-		"ASTERISK",
-		"RIGHTARROW",
-		"SLASH",
-		"BANG",
-		"LT",
-		"GT",
-		"COMMA",
-		"COLON",
-		"SEMICOLON",
-		"LPAREN",
-		"RPAREN",
-		"LBRACE",
-		"RBRACE",
-		"TYPE",
-		"LET",
-		"FUNCTION",
-		"RETURN",
-		"IF",
-		"ELSE"}[t]
+	return names[t]
+}
+
+// MarshalText encodes the token type by its name, so tokens serialize
+// to a readable and stable form.
+func (t TokenType) MarshalText() ([]byte, error) {
+	if t < 0 || int(t) >= len(names) {
+		return nil, fmt.Errorf("token: unknown token type %d", int(t))
+	}
+	return []byte(names[t]), nil
+}
+
+// UnmarshalText decodes a token type from its name.
+func (t *TokenType) UnmarshalText(text []byte) error {
+	for i, name := range names {
+		if name == string(text) {
+			*t = TokenType(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("token: unknown token type %q", text)
 }
 
 var keywords = map[string]TokenType{
-	"let":    LET,
-	"fn":     FUNCTION,
-	"return": RETURN,
-	"Int":    TYPE,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
+	"let":      LET,
+	"fn":       FUNCTION,
+	"return":   RETURN,
+	"Int":      TYPE,
+	"true":     TRUE,
+	"false":    FALSE,
+	"if":       IF,
+	"else":     ELSE,
+	"macro":    MACRO,
+	"import":   IMPORT,
+	"export":   EXPORT,
+	"while":    WHILE,
+	"for":      FOR,
+	"in":       IN,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"match":    MATCH,
 }
 
 func LookupIdent(ident string) TokenType {